@@ -2,6 +2,7 @@ package benchmarks
 
 import (
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/swaggest/form/v5"
@@ -76,6 +77,39 @@ func BenchmarkSimpleUserEncodeStruct(b *testing.B) {
 	}
 }
 
+func BenchmarkSimpleUserEncodeStructValuesEncode(b *testing.B) {
+	test := getUserStruct()
+	encoder := form.NewEncoder()
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		values, err := encoder.Encode(&test)
+		if err != nil {
+			b.Error(err)
+		}
+
+		_ = values.Encode()
+	}
+}
+
+func BenchmarkSimpleUserEncodeStructToStringBuilder(b *testing.B) {
+	test := getUserStruct()
+	encoder := form.NewEncoder()
+
+	var sb strings.Builder
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		sb.Reset()
+
+		if err := encoder.EncodeToStringBuilder(&sb, &test); err != nil {
+			b.Error(err)
+		}
+	}
+}
+
 func BenchmarkSimpleUserEncodeStructParallel(b *testing.B) {
 	test := getUserStruct()
 	encoder := form.NewEncoder()
@@ -90,6 +124,23 @@ func BenchmarkSimpleUserEncodeStructParallel(b *testing.B) {
 	})
 }
 
+// BenchmarkSimpleUserEncodeStructParallelUnshared is a baseline for BenchmarkSimpleUserEncodeStructParallel
+// without any shared state pool, similar timings show that per-P caches of sync.Pool leave no contention to shard.
+func BenchmarkSimpleUserEncodeStructParallelUnshared(b *testing.B) {
+	test := getUserStruct()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		encoder := form.NewEncoder()
+
+		for pb.Next() {
+			if _, err := encoder.Encode(&test); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
 // Primitives ALL types
 
 type PrimitivesStruct struct {
@@ -3,6 +3,7 @@ package form
 import (
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -29,10 +30,29 @@ type cachedField struct {
 	name              string
 	isAnonymous       bool
 	isOmitEmpty       bool
+	isOmitDefault     bool
+	isNoEscape        bool
+	isRequired        bool
+	maxItems          int
+	countKey          string
+	splitSuffixes     []string
+	defaultValue      *string
+	options           []string
 	isExported        bool
 	sliceSeparator    byte
 	hasExportedScalar bool
 	canSet            bool
+	isTypedInterface  bool
+	isJSONArray       bool
+	groups            []string
+	visibility        string
+	isShared          bool
+	sharedNames       []string
+	transform         TransformFunc
+	unknownTransform  string
+	emptyAs           string
+	isEncrypted       bool
+	keyCase           string
 }
 
 type cachedStruct struct {
@@ -44,6 +64,15 @@ type structCacheMap struct {
 	m     atomic.Value // map[reflect.Type]*cachedStruct
 	lock  sync.Mutex
 	tagFn TagNameFunc
+
+	// transforms are field transforms available by name for `transform=` tag option.
+	transforms map[string]TransformFunc
+
+	// contextualNameFn renames fields depending on parent struct type.
+	contextualNameFn func(parentType reflect.Type, goField string, tag string) string
+
+	// jsonFallback enables using json tag for fields without tag.
+	jsonFallback bool
 }
 
 // TagNameFunc allows for adding of a custom tag name parser.
@@ -83,6 +112,24 @@ func (s *structCacheMap) parseStruct(mode Mode, typ reflect.Type, tagName string
 	return s.ps(mode, typ, tagName)
 }
 
+// embeddedNames returns names of fields of embedded struct type, including fields of structs embedded in it,
+// that are decoded with the same namespace as the embedding struct.
+func (s *structCacheMap) embeddedNames(mode Mode, typ reflect.Type, tagName string) []string {
+	var names []string
+
+	for _, f := range s.ps(mode, typ, tagName).fields {
+		if f.isAnonymous && f.hasExportedScalar {
+			names = append(names, s.embeddedNames(mode, derefType(typ).Field(f.idx).Type, tagName)...)
+
+			continue
+		}
+
+		names = append(names, f.name)
+	}
+
+	return names
+}
+
 func (s *structCacheMap) ps(mode Mode, typ reflect.Type, tagName string) (cs *cachedStruct) {
 	// could have been multiple trying to access, but once first is done this ensures struct
 	// isn't parsed again.
@@ -116,15 +163,15 @@ func (s *structCacheMap) ps(mode Mode, typ reflect.Type, tagName string) (cs *ca
 	var (
 		fld            reflect.StructField
 		name           string
+		options        []string
 		idx            int
-		isOmitEmpty    bool
 		sliceSeparator byte
 	)
 
 	hasExportedScalar := false
 
 	for i := 0; i < numFields; i++ {
-		isOmitEmpty = false
+		options = nil
 		sliceSeparator = 0
 		fld = typ.Field(i)
 
@@ -136,6 +183,12 @@ func (s *structCacheMap) ps(mode Mode, typ reflect.Type, tagName string) (cs *ca
 			name = s.tagFn(fld)
 		} else {
 			name = fld.Tag.Get(tagName)
+
+			if name == "" && s.jsonFallback {
+				if _, ok := fld.Tag.Lookup(tagName); !ok {
+					name = fld.Tag.Get("json")
+				}
+			}
 		}
 
 		if name == ignore {
@@ -146,9 +199,9 @@ func (s *structCacheMap) ps(mode Mode, typ reflect.Type, tagName string) (cs *ca
 			continue
 		}
 
-		// check for omitempty
-		if idx = strings.LastIndexByte(name, ','); idx != -1 {
-			isOmitEmpty = name[idx+1:] == "omitempty"
+		// check for options, e.g. omitempty
+		if idx = strings.IndexByte(name, ','); idx != -1 {
+			options = strings.Split(name[idx+1:], ",")
 			name = name[:idx]
 		}
 
@@ -176,12 +229,82 @@ func (s *structCacheMap) ps(mode Mode, typ reflect.Type, tagName string) (cs *ca
 		cf.name = name
 		cf.isAnonymous = fld.Anonymous
 		cf.isExported = fld.PkgPath == ""
-		cf.isOmitEmpty = isOmitEmpty
+		cf.options = options
+
+		for _, o := range options {
+			switch o {
+			case "omitempty":
+				cf.isOmitEmpty = true
+			case "omitdefault":
+				cf.isOmitDefault = true
+			case "noescape":
+				cf.isNoEscape = true
+			case "required":
+				cf.isRequired = true
+			case "encrypt":
+				cf.isEncrypted = true
+			case "jsonarray":
+				if k := derefType(fld.Type).Kind(); k == reflect.Slice || k == reflect.Array {
+					cf.isJSONArray = true
+				}
+			default:
+				switch {
+				case strings.HasPrefix(o, "max="):
+					cf.maxItems, _ = strconv.Atoi(o[len("max="):]) //nolint:errcheck // Invalid limit is ignored.
+				case strings.HasPrefix(o, "count="):
+					cf.countKey = o[len("count="):]
+				case strings.HasPrefix(o, "keycase="):
+					cf.keyCase = o[len("keycase="):]
+				case strings.HasPrefix(o, "visibility="):
+					cf.visibility = o[len("visibility="):]
+				case strings.HasPrefix(o, "emptyas="):
+					if derefType(fld.Type).Kind() == reflect.Slice {
+						cf.emptyAs = o[len("emptyas="):]
+					}
+				case strings.HasPrefix(o, "transform="):
+					tn := o[len("transform="):]
+
+					if cf.transform = s.transforms[tn]; cf.transform == nil {
+						cf.unknownTransform = tn
+					}
+				case strings.HasPrefix(o, "group="):
+					cf.groups = strings.Split(o[len("group="):], "|")
+				case strings.HasPrefix(o, "default="):
+					dv := o[len("default="):]
+					cf.defaultValue = &dv
+				case strings.HasPrefix(o, "split="):
+					if suffixes := strings.Split(o[len("split="):], ":"); len(suffixes) == 2 && derefType(fld.Type) == timeType {
+						cf.splitSuffixes = suffixes
+					}
+				}
+			}
+		}
+
+		if s.contextualNameFn != nil {
+			if n := s.contextualNameFn(typ, fld.Name, cf.name); n != "" {
+				cf.name = n
+			}
+		}
+
+		// forced key case is taken from field tag even with custom tag name func and applies last,
+		// it is applied again to names resolved at encoding time, e.g. with FieldNamer or pluralization
+		if s.tagFn != nil && cf.keyCase == "" {
+			if opts := strings.Split(fld.Tag.Get(tagName), ","); len(opts) > 1 {
+				for _, o := range opts[1:] {
+					if strings.HasPrefix(o, "keycase=") {
+						cf.keyCase = o[len("keycase="):]
+					}
+				}
+			}
+		}
+
+		cf.name = applyKeyCase(cf.name, cf.keyCase)
+
 		cf.sliceSeparator = sliceSeparator
 		cf.canSet = true
 
 		if fld.Type.Kind() == reflect.Interface && fld.Type.NumMethod() > 0 {
-			cf.canSet = false
+			cf.isTypedInterface = true
 		}
 
 		if cf.isAnonymous && !cf.isExported && fld.Type.Kind() == reflect.Ptr {
@@ -202,6 +325,23 @@ func (s *structCacheMap) ps(mode Mode, typ reflect.Type, tagName string) (cs *ca
 		cs.fields = append(cs.fields, cf)
 	}
 
+	// fields with the same names as fields of embedded structs share keys with them
+	for j := range cs.fields {
+		ef := &cs.fields[j]
+		if !ef.isAnonymous || !ef.hasExportedScalar {
+			continue
+		}
+
+		for _, name := range s.embeddedNames(mode, typ.Field(ef.idx).Type, tagName) {
+			for i := range cs.fields {
+				if cs.fields[i].name == name && !cs.fields[i].isAnonymous {
+					cs.fields[i].isShared = true
+					ef.sharedNames = append(ef.sharedNames, name)
+				}
+			}
+		}
+	}
+
 	cs.hasExportedScalar = hasExportedScalar
 
 	return cs
@@ -1,8 +1,12 @@
 package form
 
 import (
+	"crypto/subtle"
 	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -13,27 +17,190 @@ import (
 const (
 	errArraySize = "array size of '%d' is larger than the maximum currently set on the decoder of '%d', " +
 		"see SetMaxArraySize(size uint)"
+	errArrayOverflow       = "number of values '%d' is larger than array length '%d'"
+	errArrayIndex          = "array index '%d' is out of bounds of array length '%d'"
 	errMissingStartBracket = "invalid formatting for key '%s' missing '[' bracket"
 	errMissingEndBracket   = "invalid formatting for key '%s' missing ']' bracket"
+	errPathDepth           = "key path depth of '%d' is larger than the maximum currently set on the decoder of '%d', " +
+		"see SetMaxPathDepth(depth uint)"
+	errTotalValueBytes = "total length of bound values of '%d' bytes is larger than the maximum currently set on the decoder of '%d', " +
+		"see SetMaxTotalValueBytes(n int)"
+	errAllocDepth = "struct pointer allocation depth of '%d' is larger than the maximum currently set on the decoder of '%d', " +
+		"see SetMaxAllocDepth(n int)"
 )
 
 type decoder struct {
-	d         *Decoder
-	errs      DecodeErrors
-	dm        dataMap
-	dmDone    bool
-	values    url.Values
-	goValues  map[string]interface{}
-	maxKeyLen int
-	namespace []byte
+	d          *Decoder
+	errs       DecodeErrors
+	dm         dataMap
+	dmDone     bool
+	values     url.Values
+	goValues   map[string]interface{}
+	populated  []string
+	track      bool
+	decrypt    bool
+	decrypted  map[string]struct{}
+	bound      map[string]struct{}
+	boundBytes int
+	shared     []string
+	allocs     int
+	maxKeyLen  int
+	depth      int
+	namespace  []byte
 }
 
 func (d *decoder) setError(namespace []byte, err error) {
+	var value string
+
+	if arr := d.values[string(namespace)]; len(arr) > 0 {
+		value = arr[0]
+	}
+
+	d.setFieldError(namespace, nil, value, err)
+}
+
+func (d *decoder) setFieldError(namespace []byte, typ reflect.Type, value string, err error) {
 	if d.errs == nil {
 		d.errs = make(DecodeErrors)
 	}
 
-	d.errs[string(namespace)] = err
+	d.errs[string(namespace)] = &DecodeError{
+		Namespace: string(namespace),
+		Value:     value,
+		Type:      typ,
+		Cause:     err,
+	}
+}
+
+// setPopulated records namespace of a field set from input when tracking is enabled.
+func (d *decoder) setPopulated(namespace []byte) {
+	if d.track {
+		d.populated = append(d.populated, string(namespace))
+	}
+}
+
+func (d *decoder) warn(namespace string, msg string) {
+	if d.d.warnFunc != nil {
+		d.d.warnFunc(namespace, msg)
+	}
+}
+
+// stripSequence returns values with sequence number prefix removed from keys.
+func stripSequence(values url.Values) url.Values {
+	stripped := make(url.Values, len(values))
+
+	for k, v := range values {
+		if i := strings.IndexByte(k, ':'); i > 0 && isDigits(k[:i]) {
+			k = k[i+1:]
+		}
+
+		stripped[k] = append(stripped[k], v...)
+	}
+
+	return stripped
+}
+
+// verifyChecksum sets error if checksum key is missing or does not match checksum of other values
+// and reports whether checksum is valid.
+func (d *decoder) verifyChecksum() bool {
+	if d.d.checksumFunc == nil {
+		return true
+	}
+
+	sums, ok := d.values[d.d.checksumKey]
+	if !ok || len(sums) == 0 {
+		d.setError([]byte(d.d.checksumKey), errors.New("missing checksum"))
+
+		return false
+	}
+
+	values := make(url.Values, len(d.values))
+
+	for k, v := range d.values {
+		if k != d.d.checksumKey {
+			values[k] = v
+		}
+	}
+
+	// constant time comparison does not leak how much of checksum matches
+	if subtle.ConstantTimeCompare([]byte(d.d.checksumFunc([]byte(values.Encode()))), []byte(sums[0])) != 1 {
+		d.setError([]byte(d.d.checksumKey), errors.New("checksum mismatch"))
+
+		return false
+	}
+
+	return true
+}
+
+// checkPathDepth sets errors for keys nested deeper than allowed and reports whether all keys are valid.
+func (d *decoder) checkPathDepth() bool {
+	valid := true
+
+	for k := range d.values {
+		depth := 0
+
+		for i := 0; i < len(k); i++ {
+			if k[i] == '[' || k[i] == namespaceSeparator {
+				depth++
+			}
+		}
+
+		if depth > d.d.maxPathDepth {
+			d.setError([]byte(k), fmt.Errorf(errPathDepth, depth, d.d.maxPathDepth))
+
+			valid = false
+		}
+	}
+
+	return valid
+}
+
+// hasKeys reports whether there is a key of namespace or nested in it.
+func (d *decoder) hasKeys(namespace []byte) bool {
+	ns := string(namespace)
+
+	for k := range d.values {
+		if strings.HasPrefix(k, ns) && (len(k) == len(ns) || k[len(ns)] == namespaceSeparator || k[len(ns)] == '[') {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bind adds length of values of a key that is bound to a field to the total and reports whether
+// the total is within limit, if limit is set. Values of a key are counted once.
+func (d *decoder) bind(key string, arr []string) bool {
+	if d.d.maxTotalValueBytes <= 0 {
+		return true
+	}
+
+	if _, ok := d.bound[key]; ok {
+		return true
+	}
+
+	// error is reported once, no more values are bound after limit is exceeded
+	if d.boundBytes > d.d.maxTotalValueBytes {
+		return false
+	}
+
+	if d.bound == nil {
+		d.bound = make(map[string]struct{})
+	}
+
+	d.bound[key] = struct{}{}
+
+	for _, v := range arr {
+		d.boundBytes += len(v)
+	}
+
+	if d.boundBytes > d.d.maxTotalValueBytes {
+		d.setError(nil, fmt.Errorf(errTotalValueBytes, d.boundBytes, d.d.maxTotalValueBytes))
+
+		return false
+	}
+
+	return true
 }
 
 func (d *decoder) findAlias(ns string) *recursiveData {
@@ -78,6 +245,12 @@ func (d *decoder) parseMapData() error {
 				isNum = true
 			case ']':
 				if !insideBracket {
+					if d.d.lenientKeys {
+						d.warn(k, fmt.Sprintf(errMissingStartBracket, k))
+
+						continue
+					}
+
 					return fmt.Errorf(errMissingStartBracket, k)
 				}
 
@@ -135,6 +308,14 @@ func (d *decoder) parseMapData() error {
 
 		// if still inside bracket, that means no ending bracket was ever specified
 		if insideBracket {
+			if d.d.lenientKeys {
+				d.warn(k, fmt.Sprintf(errMissingEndBracket, k))
+
+				insideBracket = false
+
+				continue
+			}
+
 			return fmt.Errorf(errMissingEndBracket, k)
 		}
 	}
@@ -153,55 +334,451 @@ func (d *decoder) traverseStruct(v reflect.Value, typ reflect.Type, namespace []
 		s = d.d.structCache.parseStruct(d.d.mode, typ, d.d.tagName)
 	}
 
+	d.depth++
+	defer func() { d.depth-- }()
+
+	// names shared with fields of embedding structs, they only apply to fields of this struct
+	shared := d.shared
+	d.shared = nil
+
 	for _, f := range s.fields {
-		if !f.canSet {
+		if !f.canSet || (f.isTypedInterface && d.d.interfaceTypes == nil) {
 			continue
 		}
 
 		namespace = namespace[:l]
 
 		if f.isAnonymous && f.hasExportedScalar {
+			d.shared = append(append([]string(nil), f.sharedNames...), shared...)
+
 			if d.setFieldByType(v.Field(f.idx), false, namespace, 0) {
 				set = true
 			}
+
+			d.shared = nil
 		}
 
+		name := applyKeyCase(sliceKey(f.name, typ.Field(f.idx).Type, d.d.pluralize), f.keyCase)
+
 		if first {
-			namespace = append(namespace, f.name...)
+			namespace = append(namespace, name...)
 		} else {
 			namespace = append(namespace, namespaceSeparator)
-			namespace = append(namespace, f.name...)
+			namespace = append(namespace, name...)
 		}
 
 		if f.sliceSeparator != 0 {
-			if len(d.values[f.name]) > 0 {
-				d.values[f.name] = strings.Split(d.values[f.name][0], string(f.sliceSeparator))
+			if len(d.values[name]) > 0 {
+				d.values[name] = strings.Split(d.values[name][0], string(f.sliceSeparator))
+			}
+		}
+
+		// keys of nested fields are already decrypted with encrypted parent field
+		if f.isEncrypted && !d.decrypt && !d.decryptValues(namespace) {
+			continue
+		}
+
+		if f.splitSuffixes != nil {
+			if d.setSplitTime(v.Field(f.idx), namespace, f) {
+				d.setPopulated(namespace)
+
+				set = true
 			}
+
+			continue
 		}
 
+		if f.defaultValue != nil && d.d.defaultsMode != DefaultsIgnore {
+			d.setDefault(v.Field(f.idx), namespace, *f.defaultValue)
+		}
+
+		if f.isJSONArray {
+			if d.setJSONArray(v.Field(f.idx), namespace) {
+				d.setPopulated(namespace)
+
+				set = true
+			}
+
+			continue
+		}
+
+		if f.emptyAs != "" && d.setEmptySlice(v.Field(f.idx), namespace, len(namespace)-len(name), f.emptyAs) {
+			d.setPopulated(namespace)
+
+			set = true
+
+			continue
+		}
+
+		if !f.isShared && !hasName(shared, f.name) && !d.checkShape(typ.Field(f.idx).Type, namespace) {
+			continue
+		}
+
+		decrypt := d.decrypt
+		d.decrypt = decrypt || f.isEncrypted
+
 		if d.setFieldByType(v.Field(f.idx), false, namespace, 0) {
 			if d.goValues != nil {
 				d.goValues[f.name] = v.Field(f.idx).Interface()
 			}
 
+			d.setPopulated(namespace)
+
 			set = true
 		}
+
+		d.decrypt = decrypt
 	}
 
 	return set
 }
 
+// checkRoundTrip reports whether scalar v formats back to input value when strict round trip is enabled,
+// and sets an error otherwise.
+func (d *decoder) checkRoundTrip(v reflect.Value, namespace []byte, value string) bool {
+	if !d.d.strictRoundTrip {
+		return true
+	}
+
+	var s string
+
+	switch v.Kind() { //nolint:exhaustive // Only scalars are checked.
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	case reflect.Bool:
+		// custom parser defines its own accepted forms, which do not have to format back
+		if d.d.boolParseFunc != nil {
+			return true
+		}
+
+		s = strconv.FormatBool(v.Bool())
+
+		if d.d.caseInsensitive && strings.EqualFold(s, value) {
+			return true
+		}
+	default:
+		return true
+	}
+
+	if s != value {
+		d.setFieldError(namespace, v.Type(), value, fmt.Errorf("value '%s' type '%v' namespace '%s' does not round trip, "+
+			"expected '%s'", value, v.Type(), string(namespace), s))
+
+		return false
+	}
+
+	return true
+}
+
+// setLocaleNumber sets integer or float v from value parsed with locale number parser.
+func (d *decoder) setLocaleNumber(v reflect.Value, namespace []byte, value string) bool {
+	f, err := d.d.numberLocale(value)
+
+	switch v.Kind() { //nolint:exhaustive // Only numbers are set.
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if err == nil && (f != math.Trunc(f) || v.OverflowInt(int64(f))) {
+			err = fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'", value, v.Type(), string(namespace))
+		}
+
+		if err == nil {
+			v.SetInt(int64(f))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if err == nil && (f < 0 || f != math.Trunc(f) || v.OverflowUint(uint64(f))) {
+			err = fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'", value, v.Type(), string(namespace))
+		}
+
+		if err == nil {
+			v.SetUint(uint64(f))
+		}
+	default:
+		if err == nil && v.OverflowFloat(f) {
+			err = fmt.Errorf("invalid float value '%s' type '%v' namespace '%s'", value, v.Type(), string(namespace))
+		}
+
+		if err == nil {
+			v.SetFloat(f)
+		}
+	}
+
+	if err != nil {
+		d.setFieldError(namespace, v.Type(), value, err)
+
+		return false
+	}
+
+	return true
+}
+
+// checkShape reports whether values of namespace match shape of field type, a scalar field expects a single value.
+//
+// With shape coercion, mismatches are reported as warnings: first of multiple values is used for a scalar field
+// and a single value is wrapped into a one-element slice.
+func (d *decoder) checkShape(t reflect.Type, namespace []byte) bool {
+	arr := d.values[string(namespace)]
+	t = derefType(t)
+
+	switch t.Kind() { //nolint:exhaustive // Only containers are distinguished from scalars.
+	case reflect.Map, reflect.Interface:
+		return true
+	case reflect.Slice, reflect.Array:
+		if d.d.shapeCoercion && len(arr) == 1 && t.Elem().Kind() != reflect.Uint8 {
+			d.warn(string(namespace), "single value is decoded as a one-element slice")
+		}
+
+		return true
+	case reflect.Struct:
+		if !isTimeType(t) {
+			return true
+		}
+	}
+
+	if len(arr) < 2 {
+		return true
+	}
+
+	if d.d.shapeCoercion {
+		d.warn(string(namespace), fmt.Sprintf("%d values for a scalar field, using the first one", len(arr)))
+
+		return true
+	}
+
+	d.setError(namespace, fmt.Errorf("%d values for a scalar field of type '%v' namespace '%s', a single value expected",
+		len(arr), t, string(namespace)))
+
+	return false
+}
+
+// setEmptySlice sets an empty slice to v if only marker key of field with emptyas tag option is present,
+// marker replaces field name, which starts at nameStart of namespace.
+func (d *decoder) setEmptySlice(v reflect.Value, namespace []byte, nameStart int, emptyAs string) bool {
+	if _, ok := d.values[string(namespace)]; ok {
+		return false
+	}
+
+	marker := make([]byte, 0, nameStart+len(emptyAs))
+	marker = append(append(marker, namespace[:nameStart]...), emptyAs...)
+
+	if _, ok := d.values[string(marker)]; !ok {
+		return false
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		v = v.Elem()
+	}
+
+	v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+
+	return true
+}
+
+// decryptValues replaces values of field with `encrypt` tag option and its nested keys with decrypted values,
+// it returns false if decryption fails. Keys shared with fields of embedded structs are decrypted once.
+func (d *decoder) decryptValues(namespace []byte) bool {
+	ns := string(namespace)
+
+	// input values are copied on first change to keep them intact for the caller
+	if d.decrypted == nil {
+		values := make(url.Values, len(d.values))
+
+		for k, arr := range d.values {
+			values[k] = arr
+		}
+
+		d.values = values
+		d.decrypted = make(map[string]struct{})
+	}
+
+	for k, arr := range d.values {
+		if k != ns && (!strings.HasPrefix(k, ns) || (k[len(ns)] != namespaceSeparator && k[len(ns)] != '[')) {
+			continue
+		}
+
+		if _, ok := d.decrypted[k]; ok {
+			continue
+		}
+
+		if d.d.decryptFunc == nil {
+			d.setError(namespace, fmt.Errorf("missing decrypt func for field with encrypt tag option namespace '%s'", ns))
+
+			return false
+		}
+
+		decrypted := make([]string, len(arr))
+
+		for i, s := range arr {
+			p, err := d.d.decryptFunc(s)
+			if err != nil {
+				d.setFieldError([]byte(k), nil, s, err)
+
+				return false
+			}
+
+			decrypted[i] = p
+		}
+
+		d.values[k] = decrypted
+		d.decrypted[k] = struct{}{}
+	}
+
+	return true
+}
+
+// setRepeatedParents sets struct slice v from repeated keys of element fields, e.g. "items.name=a&items.name=b",
+// element i is decoded from i-th value of every key with namespace prefix.
+func (d *decoder) setRepeatedParents(v reflect.Value, namespace []byte) bool {
+	prefix := string(namespace) + string(namespaceSeparator)
+	n := 0
+
+	for k, arr := range d.values {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+
+		if !d.bind(k, arr) {
+			return false
+		}
+
+		if len(arr) > n {
+			n = len(arr)
+		}
+	}
+
+	if n == 0 {
+		return false
+	}
+
+	if n > d.d.maxArraySize {
+		d.setError(namespace, fmt.Errorf(errArraySize, n, d.d.maxArraySize))
+
+		return false
+	}
+
+	// grouped elements are appended to existing ones, like repeated values of a key
+	ol := 0
+	if !v.IsNil() {
+		ol = v.Len()
+	}
+
+	varr := reflect.MakeSlice(v.Type(), ol+n, ol+n)
+	reflect.Copy(varr, v)
+
+	for i := 0; i < n; i++ {
+		values := make(url.Values)
+
+		for k, arr := range d.values {
+			if strings.HasPrefix(k, prefix) && i < len(arr) {
+				values[k] = arr[i : i+1]
+			}
+		}
+
+		// element decoder inherits limits and tracking state of the parent decoder
+		dd := decoder{
+			d:        d.d,
+			values:   values,
+			goValues: d.goValues,
+			track:    d.track,
+			allocs:   d.allocs,
+			depth:    d.depth,
+			decrypt:  d.decrypt,
+
+			// grouped keys are already bound by the parent decoder
+			bound:      d.bound,
+			boundBytes: d.boundBytes,
+		}
+		dd.setFieldByType(varr.Index(ol+i), false, namespace, 0)
+
+		for _, ns := range dd.populated {
+			if !hasName(d.populated, ns) {
+				d.populated = append(d.populated, ns)
+			}
+		}
+
+		for k, err := range dd.errs {
+			if d.errs == nil {
+				d.errs = make(DecodeErrors)
+			}
+
+			d.errs[k] = err
+		}
+	}
+
+	v.Set(varr)
+
+	return true
+}
+
+// setDefault sets v to default value of field tag according to defaults mode.
+func (d *decoder) setDefault(v reflect.Value, namespace []byte, def string) {
+	if _, ok := d.values[string(namespace)]; ok && d.d.defaultsMode == DefaultsAbsent {
+		return
+	}
+
+	ns := string(namespace)
+	dd := decoder{d: d.d, values: url.Values{ns: {def}}}
+	dd.setFieldByType(v, false, namespace, 0)
+
+	if err, ok := dd.errs[ns]; ok {
+		d.setFieldError(namespace, v.Type(), def, fmt.Errorf("invalid default value: %w", errors.Unwrap(err)))
+	}
+}
+
+// setJSONArray sets a slice or array field from a JSON array value of field with jsonarray tag option.
+func (d *decoder) setJSONArray(v reflect.Value, namespace []byte) bool {
+	arr, ok := d.values[string(namespace)]
+	if !ok || len(arr) == 0 || arr[0] == "" || !d.bind(string(namespace), arr) {
+		return false
+	}
+
+	if err := json.Unmarshal([]byte(arr[0]), v.Addr().Interface()); err != nil {
+		d.setFieldError(namespace, v.Type(), arr[0], err)
+
+		return false
+	}
+
+	return true
+}
+
 //nolint:maintidx // This function is indeed a bit large, but sequentially structured.
 func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []byte, idx int) bool {
 	v, kind := ExtractType(current)
 	arr, ok := d.values[string(namespace)]
 
+	if ok && d.d.maxTotalValueBytes > 0 && !d.bind(string(namespace), arr) {
+		return false
+	}
+
+	if (!ok || idx < len(arr)) && current.Kind() != reflect.Ptr && current.CanAddr() && current.CanInterface() {
+		// nested keys, e.g. "o.a" of Optional struct value, also make field present
+		if ps, isPresence := current.Addr().Interface().(PresenceSetter); isPresence && (ok || d.hasKeys(namespace)) {
+			ps.SetPresence(true)
+
+			if o, isOptional := ps.(optionalDecoder); isOptional {
+				if ok && arr[idx] == "" {
+					o.setNull()
+
+					return true
+				}
+
+				return d.setFieldByType(reflect.ValueOf(o.optionalValue()).Elem(), false, namespace, idx)
+			}
+		}
+	}
+
 	if d.d.customTypeFuncs != nil {
 		if ok {
 			if cf, ok := d.d.customTypeFuncs[v.Type()]; ok {
 				val, err := cf(arr[idx])
 				if err != nil {
-					d.setError(namespace, err)
+					d.setFieldError(namespace, v.Type(), arr[idx], err)
 
 					return false
 				}
@@ -213,6 +790,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 		}
 	}
 
+	// wrappers embedding time.Time are decoded with their own or promoted encoding.TextUnmarshaler
 	if v.Type() == timeType {
 		if !ok || len(arr[idx]) == 0 {
 			return false
@@ -222,9 +800,9 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 			return false
 		}
 
-		t, err := time.Parse(time.RFC3339, arr[idx])
+		t, err := d.parseTime(arr[idx])
 		if err != nil {
-			d.setError(namespace, err)
+			d.setFieldError(namespace, v.Type(), arr[idx], err)
 
 			return false
 		}
@@ -236,19 +814,51 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 	if ok {
 		if tu, ok := current.Addr().Interface().(encoding.TextUnmarshaler); ok {
-			if err := tu.UnmarshalText([]byte(arr[idx])); err != nil {
-				d.setError(namespace, err)
+			if err := d.unmarshalText(tu, arr[idx]); err != nil {
+				d.setFieldError(namespace, v.Type(), arr[idx], err)
 
 				return false
 			}
 
 			return true
 		}
+
+		if d.d.useJSONUnmarshaler && idx < len(arr) {
+			if ju, ok := current.Addr().Interface().(json.Unmarshaler); ok {
+				if arr[idx] == "" {
+					return false
+				}
+
+				if err := ju.UnmarshalJSON([]byte(arr[idx])); err != nil {
+					d.setFieldError(namespace, v.Type(), arr[idx], err)
+
+					return false
+				}
+
+				return true
+			}
+		}
+	}
+
+	if d.d.numberLocale != nil {
+		if _, isNumber := numberValue(v); isNumber {
+			if !ok || idx == len(arr) || len(arr[idx]) == 0 {
+				return false
+			}
+
+			return d.setLocaleNumber(v, namespace, arr[idx])
+		}
 	}
 
 	switch kind {
 	case reflect.Interface:
-		if !ok || idx == len(arr) {
+		if d.d.interfaceTypes != nil {
+			if set, done := d.setInterface(v, namespace); done {
+				return set
+			}
+		}
+
+		if !ok || idx == len(arr) || v.Type().NumMethod() > 0 {
 			return false
 		}
 
@@ -257,6 +867,19 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 		return true
 
 	case reflect.Ptr:
+		if d.d.maxAllocDepth > 0 && isStructType(v.Type()) {
+			if d.allocs >= d.d.maxAllocDepth {
+				if d.hasKeys(namespace) {
+					d.setError(namespace, fmt.Errorf(errAllocDepth, d.allocs+1, d.d.maxAllocDepth))
+				}
+
+				return false
+			}
+
+			d.allocs++
+			defer func() { d.allocs-- }()
+		}
+
 		newVal := reflect.New(v.Type().Elem())
 		if set := d.setFieldByType(newVal.Elem(), true, namespace, idx); set {
 			v.Set(newVal)
@@ -264,11 +887,20 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 			return set
 		}
 
+		// pointed value is already handled, including fallback, so errors and skipped values are kept as is
+		return false
+
 	case reflect.String:
 		if !ok || idx == len(arr) {
 			return false
 		}
 
+		if d.d.nullString != nil && arr[idx] == *d.d.nullString {
+			v.SetString("")
+
+			return true
+		}
+
 		v.SetString(arr[idx])
 
 		return true
@@ -280,7 +912,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		u64, err := strconv.ParseUint(arr[idx], 10, 64)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -288,7 +920,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetUint(u64)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Uint8:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -297,7 +929,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		u64, err := strconv.ParseUint(arr[idx], 10, 8)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -305,7 +937,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetUint(u64)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Uint16:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -314,7 +946,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		u64, err := strconv.ParseUint(arr[idx], 10, 16)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -322,7 +954,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetUint(u64)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Uint32:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -331,7 +963,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		u64, err := strconv.ParseUint(arr[idx], 10, 32)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid unsigned integer value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -339,7 +971,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetUint(u64)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Int, reflect.Int64:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -348,7 +980,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		i64, err := strconv.ParseInt(arr[idx], 10, 64)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -356,7 +988,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetInt(i64)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Int8:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -365,7 +997,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		i64, err := strconv.ParseInt(arr[idx], 10, 8)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -373,7 +1005,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetInt(i64)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Int16:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -382,7 +1014,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		i64, err := strconv.ParseInt(arr[idx], 10, 16)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -390,7 +1022,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetInt(i64)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Int32:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -399,7 +1031,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		i64, err := strconv.ParseInt(arr[idx], 10, 32)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid integer value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -407,7 +1039,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetInt(i64)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Float32:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -416,7 +1048,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		f, err := strconv.ParseFloat(arr[idx], 32)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid float value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid float value '%s' type '%v' namespace '%s'",
 				arr[0], v.Type(), string(namespace)))
 
 			return false
@@ -424,7 +1056,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetFloat(f)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Float64:
 		if !ok || idx == len(arr) || len(arr[idx]) == 0 {
@@ -433,7 +1065,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		f, err := strconv.ParseFloat(arr[idx], 64)
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid float value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid float value '%s' type '%v' namespace '%s'",
 				arr[0], v.Type(), string(namespace)))
 
 			return false
@@ -441,16 +1073,16 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetFloat(f)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Bool:
 		if !ok || idx == len(arr) || (isPtr && arr[idx] == "") {
 			return false
 		}
 
-		b, err := parseBool(arr[idx])
+		b, err := d.parseBool(arr[idx])
 		if err != nil {
-			d.setError(namespace, fmt.Errorf("invalid boolean value '%s' type '%v' namespace '%s'",
+			d.setFieldError(namespace, v.Type(), arr[idx], fmt.Errorf("invalid boolean value '%s' type '%v' namespace '%s'",
 				arr[idx], v.Type(), string(namespace)))
 
 			return false
@@ -458,7 +1090,7 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 		v.SetBool(b)
 
-		return true
+		return d.checkRoundTrip(v, namespace, arr[idx])
 
 	case reflect.Slice:
 		// check arr, current
@@ -507,7 +1139,12 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 		}
 
 		// maybe it's an numbered array i.e. Phone[0].Number
-		if rd := d.findAlias(string(namespace)); rd != nil {
+		rd := d.findAlias(string(namespace))
+		if rd == nil && !set && d.d.groupRepeated && isStructType(v.Type().Elem()) {
+			return d.setRepeatedParents(v, namespace)
+		}
+
+		if rd != nil {
 			var (
 				varr reflect.Value
 				kv   key
@@ -591,9 +1228,15 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 			overCapacity := v.Len() < l
 
 			if overCapacity {
+				if d.d.arrayOverflow == ArrayOverflowError {
+					d.setError(namespace, fmt.Errorf(errArrayOverflow, l, v.Len()))
+
+					return false
+				}
+
 				// more values than array capacity, ignore values over capacity as it's possible some would just want
-				// to grab the first x number of elements; in the future strict mode logic should return an error
-				fmt.Println("warning number of post form array values is larger than array capacity, ignoring overflow values")
+				// to grab the first x number of elements
+				d.warn(string(namespace), fmt.Sprintf(errArrayOverflow+", ignoring overflow values", l, v.Len()))
 			}
 
 			varr = reflect.Indirect(reflect.New(reflect.ArrayOf(v.Len(), v.Type().Elem())))
@@ -624,9 +1267,15 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 
 			overCapacity := rd.sliceLen >= v.Len()
 			if overCapacity {
+				if d.d.arrayOverflow == ArrayOverflowError {
+					d.setError(namespace, fmt.Errorf(errArrayIndex, rd.sliceLen, v.Len()))
+
+					return false
+				}
+
 				// more values than array capacity, ignore values over capacity as it's possible some would just want
-				// to grab the first x number of elements; in the future strict mode logic should return an error
-				fmt.Println("warning number of post form array values is larger than array capacity, ignoring overflow values")
+				// to grab the first x number of elements
+				d.warn(string(namespace), fmt.Sprintf(errArrayIndex+", ignoring overflow values", rd.sliceLen, v.Len()))
 			}
 
 			varr = reflect.Indirect(reflect.New(reflect.ArrayOf(v.Len(), v.Type().Elem())))
@@ -700,8 +1349,23 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 			newVal := reflect.New(typ.Elem()).Elem()
 			mk = reflect.New(typ.Key()).Elem()
 			kv = rd.keys[i]
+			k := kv.value
+
+			if d.d.escapeMapKeys {
+				var err error
+
+				if k, err = url.PathUnescape(k); err != nil {
+					d.setError(namespace, fmt.Errorf("invalid escaped map key '%s': %w", kv.value, err))
+
+					continue
+				}
+			}
 
-			if err := d.getMapKey(kv.value, mk, namespace); err != nil {
+			if d.d.mapKeyTransform != nil && typ.Key().Kind() == reflect.String {
+				k = d.d.mapKeyTransform(k)
+			}
+
+			if err := d.getMapKey(k, mk, namespace); err != nil {
 				d.setError(namespace, err)
 
 				continue
@@ -723,6 +1387,24 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 		return true
 
 	case reflect.Struct:
+		if d.d.emptyStruct != "" && ok && idx < len(arr) && arr[idx] == d.d.emptyStruct {
+			return true
+		}
+
+		if d.d.jsonBelowDepth > 0 && d.depth > d.d.jsonBelowDepth {
+			if !ok || idx == len(arr) || arr[idx] == "" {
+				return false
+			}
+
+			if err := json.Unmarshal([]byte(arr[idx]), v.Addr().Interface()); err != nil {
+				d.setFieldError(namespace, v.Type(), arr[idx], err)
+
+				return false
+			}
+
+			return true
+		}
+
 		if err := d.parseMapData(); err != nil {
 			d.setError(namespace, fmt.Errorf("failed to parse map data: %w", err))
 
@@ -737,9 +1419,160 @@ func (d *decoder) setFieldByType(current reflect.Value, isPtr bool, namespace []
 		return d.traverseStruct(v, v.Type(), namespace)
 	}
 
+	if d.d.fallbackFunc != nil && ok && idx < len(arr) {
+		if err := d.d.fallbackFunc(arr[idx], v); err != nil {
+			d.setFieldError(namespace, v.Type(), arr[idx], err)
+
+			return false
+		}
+
+		return true
+	}
+
 	return false
 }
 
+// setInterface decodes a value of concrete type registered for discriminator in input values.
+func (d *decoder) setInterface(v reflect.Value, namespace []byte) (set bool, done bool) {
+	typeKey := string(namespace) + string(namespaceSeparator) + discriminatorKey
+
+	names, ok := d.values[typeKey]
+	if !ok || len(names) == 0 {
+		if v.Type().NumMethod() == 0 {
+			return false, false
+		}
+
+		prefix := string(namespace)
+
+		for k := range d.values {
+			if len(k) > len(prefix) && strings.HasPrefix(k, prefix) && (k[len(prefix)] == namespaceSeparator || k[len(prefix)] == '[') {
+				d.setError(namespace, fmt.Errorf("missing discriminator '%s' for interface '%v' namespace '%s'",
+					discriminatorKey, v.Type(), prefix))
+
+				break
+			}
+		}
+
+		return false, true
+	}
+
+	typ, ok := d.d.interfaceTypes[names[0]]
+	if !ok {
+		d.setError(namespace, fmt.Errorf("unknown discriminator '%s' for interface '%v' namespace '%s'",
+			names[0], v.Type(), string(namespace)))
+
+		return false, true
+	}
+
+	if !typ.Implements(v.Type()) {
+		d.setError(namespace, fmt.Errorf("type '%v' of discriminator '%s' does not implement '%v' namespace '%s'",
+			typ, names[0], v.Type(), string(namespace)))
+
+		return false, true
+	}
+
+	newVal := reflect.New(typ).Elem()
+	d.setFieldByType(newVal, false, namespace, 0)
+	v.Set(newVal)
+
+	return true, true
+}
+
+func (d *decoder) parseBool(s string) (bool, error) {
+	if d.d.boolParseFunc != nil {
+		return d.d.boolParseFunc(s)
+	}
+
+	if d.d.caseInsensitive {
+		if b, err := parseBool(strings.ToLower(s)); err == nil {
+			return b, nil
+		}
+	}
+
+	return parseBool(s)
+}
+
+// unmarshalText unmarshals value as is and then in lower and upper case if decoder is case-insensitive,
+// the error of value as is is returned if all attempts fail.
+func (d *decoder) unmarshalText(tu encoding.TextUnmarshaler, s string) error {
+	err := tu.UnmarshalText([]byte(s))
+	if err == nil || !d.d.caseInsensitive {
+		return err
+	}
+
+	for _, cs := range []string{strings.ToLower(s), strings.ToUpper(s)} {
+		if cs != s && tu.UnmarshalText([]byte(cs)) == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// setSplitTime combines date and time parts from namespace suffixes of split option into time value.
+func (d *decoder) setSplitTime(v reflect.Value, namespace []byte, f cachedField) bool {
+	ns := string(namespace)
+
+	dates, ok := d.values[ns+"_"+f.splitSuffixes[0]]
+	if !ok || len(dates) == 0 || dates[0] == "" || !d.bind(ns+"_"+f.splitSuffixes[0], dates) {
+		return false
+	}
+
+	layout, s := splitDateLayout, dates[0]
+
+	times := d.values[ns+"_"+f.splitSuffixes[1]]
+	if !d.bind(ns+"_"+f.splitSuffixes[1], times) {
+		return false
+	}
+
+	if len(times) > 0 && times[0] != "" {
+		layout, s = splitDateLayout+" "+splitTimeLayout, s+" "+times[0]
+	}
+
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		d.setError(namespace, err)
+
+		return false
+	}
+
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(timeType))
+		v = v.Elem()
+	}
+
+	v.Set(reflect.ValueOf(t))
+
+	return true
+}
+
+func (d *decoder) parseTime(s string) (time.Time, error) {
+	var firstErr error
+
+	for _, layout := range d.d.timeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if d.d.timeAutoDetect && isDigits(s) {
+		if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC(), nil
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no time layout to parse '%s'", s)
+	}
+
+	return time.Time{}, firstErr
+}
+
 func (d *decoder) getMapKey(key string, current reflect.Value, namespace []byte) (err error) {
 	v, kind := ExtractType(current)
 
@@ -855,7 +1688,7 @@ func (d *decoder) getMapKey(key string, current reflect.Value, namespace []byte)
 		v.SetFloat(f)
 
 	case reflect.Bool:
-		b, e := parseBool(key)
+		b, e := d.parseBool(key)
 		if e != nil {
 			return fmt.Errorf("invalid boolean value '%s' type '%v' namespace '%s'", key, v.Type(), string(namespace))
 		}
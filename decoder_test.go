@@ -1,8 +1,10 @@
 package form
 
 import (
+	"crypto/sha256"
 	"encoding"
 	"errors"
+	"fmt"
 	"net/url"
 	"reflect"
 	"strings"
@@ -1832,3 +1834,1116 @@ func TestDecoder_EmptyArrayBool(t *testing.T) {
 	err := d.Decode(v, in)
 	Equal(t, err, nil)
 }
+
+func TestDecoder_SetFallbackFunc(t *testing.T) {
+	t.Parallel()
+
+	type point complex128
+
+	type TestStruct struct {
+		Point  point         `form:"point"`
+		Points []complex64   `form:"points"`
+		Ptr    *point        `form:"ptr"`
+		Name   string        `form:"name"`
+		Skip   chan struct{} `form:"skip"`
+	}
+
+	d := NewDecoder()
+	d.SetFallbackFunc(func(val string, v reflect.Value) error {
+		if v.Kind() != reflect.Complex64 && v.Kind() != reflect.Complex128 {
+			return errors.New("unsupported kind " + v.Kind().String())
+		}
+
+		var c complex128
+		if _, err := fmt.Sscan(val, &c); err != nil {
+			return err
+		}
+
+		v.SetComplex(c)
+
+		return nil
+	})
+
+	var v TestStruct
+	err := d.Decode(&v, url.Values{
+		"point":  {"1+2i"},
+		"points": {"3+4i", "5+0i"},
+		"ptr":    {"0+6i"},
+		"name":   {"foo"},
+	})
+	Equal(t, err, nil)
+	Equal(t, v.Point, point(1+2i))
+	Equal(t, v.Points, []complex64{3 + 4i, 5})
+	Equal(t, *v.Ptr, point(6i))
+	Equal(t, v.Name, "foo")
+
+	err = d.Decode(&v, url.Values{"point": {"abc"}, "skip": {"1"}})
+	NotEqual(t, err, nil)
+	Equal(t, len(err.(DecodeErrors)), 2)
+	NotEqual(t, err.(DecodeErrors)["point"], nil)
+	Equal(t, err.(DecodeErrors)["skip"].Error(), "unsupported kind chan")
+
+	// pointers to built-in kinds keep their own errors and skip empty values
+	type PtrStruct struct {
+		Int  *int  `form:"int"`
+		Bool *bool `form:"bool"`
+	}
+
+	var p PtrStruct
+
+	err = d.Decode(&p, url.Values{"int": {"abc"}, "bool": {""}})
+	NotEqual(t, err, nil)
+	Equal(t, len(err.(DecodeErrors)), 1)
+	Equal(t, err.(DecodeErrors)["int"].Error(), "invalid integer value 'abc' type 'int' namespace 'int'")
+	Equal(t, p.Bool, (*bool)(nil))
+}
+
+func TestDecoder_SetTimeAutoDetect(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Time  time.Time    `form:"time"`
+		Times []*time.Time `form:"times"`
+	}
+
+	d := NewDecoder()
+	d.SetTimeLayouts(time.RFC3339, "2006-01-02")
+
+	var tst Test
+
+	err := d.Decode(&tst, url.Values{"time": {"1700000000"}})
+	NotEqual(t, err, nil)
+
+	d.SetTimeAutoDetect(true)
+
+	for _, s := range []string{"2023-11-14T22:13:20Z", "2023-11-14", "1700000000"} {
+		tst = Test{}
+		err = d.Decode(&tst, url.Values{"time": {s}})
+		Equal(t, err, nil)
+
+		if s == "2023-11-14" {
+			Equal(t, tst.Time, time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC))
+		} else {
+			Equal(t, tst.Time, time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC))
+		}
+	}
+
+	tst = Test{}
+	err = d.Decode(&tst, url.Values{"times": {"2023-11-14", "0"}})
+	Equal(t, err, nil)
+	Equal(t, len(tst.Times), 2)
+	Equal(t, *tst.Times[0], time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC))
+	Equal(t, *tst.Times[1], time.Unix(0, 0).UTC())
+
+	err = d.Decode(&tst, url.Values{"time": {"-1"}})
+	NotEqual(t, err, nil)
+	Contains(t, err.Error(), "cannot parse")
+}
+
+func TestDecoder_SetFallbackToJSONTag(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name    string `json:"name"`
+		Empty   string `json:"empty,omitempty"`
+		Skipped string `json:"-"`
+		Form    string `json:"json_form" form:"form"`
+		NoTag   int
+		Inner   struct {
+			Value string `json:"value"`
+		} `json:"inner"`
+	}
+
+	values := url.Values{
+		"name":        {"foo"},
+		"empty":       {"bar"},
+		"Skipped":     {"baz"},
+		"-":           {"baz"},
+		"json_form":   {"wrong"},
+		"form":        {"qux"},
+		"NoTag":       {"1"},
+		"inner.value": {"quux"},
+	}
+
+	d := NewDecoder()
+	d.SetFallbackToJSONTag(true)
+
+	var tst Test
+
+	err := d.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst.Name, "foo")
+	Equal(t, tst.Empty, "bar")
+	Equal(t, tst.Skipped, "")
+	Equal(t, tst.Form, "qux")
+	Equal(t, tst.NoTag, 1)
+	Equal(t, tst.Inner.Value, "quux")
+
+	e := NewEncoder()
+	e.SetFallbackToJSONTag(true)
+
+	encoded, err := e.Encode(tst)
+	Equal(t, err, nil)
+
+	var decoded Test
+
+	err = d.Decode(&decoded, encoded)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestDecoder_SetEscapeMapKeys(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		Value string `form:"value"`
+	}
+
+	type Test struct {
+		Map    map[string]int   `form:"map"`
+		Nested map[string]Inner `form:"nested"`
+	}
+
+	tst := Test{
+		Map:    map[string]int{"a.b": 1, "c[d]": 2, "50%": 3, "plain": 4},
+		Nested: map[string]Inner{"x.y": {Value: "z"}},
+	}
+
+	e := NewEncoder()
+	e.SetEscapeMapKeys(true)
+
+	values, err := e.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"map[a%2Eb]":          {"1"},
+		"map[c%5Bd%5D]":       {"2"},
+		"map[50%25]":          {"3"},
+		"map[plain]":          {"4"},
+		"nested[x%2Ey].value": {"z"},
+	})
+
+	d := NewDecoder()
+	d.SetEscapeMapKeys(true)
+
+	var decoded Test
+
+	err = d.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	decoded = Test{}
+	err = d.Decode(&decoded, url.Values{"map[%zz]": {"1"}})
+	NotEqual(t, err, nil)
+	Contains(t, err.Error(), "invalid escaped map key '%zz'")
+}
+
+func TestDecoder_SetMapKeyTransform(t *testing.T) {
+	t.Parallel()
+
+	toSnake := func(s string) string {
+		var b strings.Builder
+
+		for _, r := range s {
+			if r >= 'A' && r <= 'Z' {
+				b.WriteByte('_')
+				r += 'a' - 'A'
+			}
+
+			b.WriteRune(r)
+		}
+
+		return b.String()
+	}
+
+	toCamel := func(s string) string {
+		parts := strings.Split(s, "_")
+
+		for i := 1; i < len(parts); i++ {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+
+		return strings.Join(parts, "")
+	}
+
+	type Test struct {
+		FieldName map[string]string `form:"fieldName"`
+		IntKeys   map[int]string    `form:"intKeys"`
+	}
+
+	tst := Test{
+		FieldName: map[string]string{"firstName": "John", "lastName": "Doe"},
+		IntKeys:   map[int]string{1: "one"},
+	}
+
+	e := NewEncoder()
+	e.SetMapKeyTransform(toSnake)
+
+	values, err := e.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"fieldName[first_name]": {"John"},
+		"fieldName[last_name]":  {"Doe"},
+		"intKeys[1]":            {"one"},
+	})
+
+	d := NewDecoder()
+	d.SetMapKeyTransform(toCamel)
+
+	var decoded Test
+
+	err = d.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestDecoder_SetArrayOverflowMode(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Array [3]int `form:"array"`
+	}
+
+	d := NewDecoder()
+
+	var tst Test
+
+	err := d.Decode(&tst, url.Values{"array[0]": {"1"}, "array[2]": {"3"}})
+	Equal(t, err, nil)
+	Equal(t, tst.Array, [3]int{1, 0, 3})
+
+	tst = Test{}
+	err = d.Decode(&tst, url.Values{"array[1]": {"2"}, "array[3]": {"4"}})
+	Equal(t, err, nil)
+	Equal(t, tst.Array, [3]int{0, 2, 0})
+
+	var warnings []string
+
+	wd := NewDecoder()
+	wd.SetWarnFunc(func(namespace, msg string) {
+		warnings = append(warnings, namespace+": "+msg)
+	})
+
+	tst = Test{}
+	err = wd.Decode(&tst, url.Values{"array": {"1", "2", "3", "4"}})
+	Equal(t, err, nil)
+	Equal(t, tst.Array, [3]int{1, 2, 3})
+
+	err = wd.Decode(&tst, url.Values{"array[3]": {"4"}})
+	Equal(t, err, nil)
+	Equal(t, warnings, []string{
+		"array: number of values '4' is larger than array length '3', ignoring overflow values",
+		"array: array index '3' is out of bounds of array length '3', ignoring overflow values",
+	})
+
+	d.SetArrayOverflowMode(ArrayOverflowError)
+
+	tst = Test{}
+	err = d.Decode(&tst, url.Values{"array[0]": {"1"}, "array[2]": {"3"}})
+	Equal(t, err, nil)
+	Equal(t, tst.Array, [3]int{1, 0, 3})
+
+	tst = Test{}
+	err = d.Decode(&tst, url.Values{"array[1]": {"2"}, "array[3]": {"4"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:array ERROR:array index '3' is out of bounds of array length '3'")
+	Equal(t, tst.Array, [3]int{})
+
+	err = d.Decode(&tst, url.Values{"array": {"1", "2", "3", "4"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:array ERROR:number of values '4' is larger than array length '3'")
+}
+
+type testShape interface {
+	Area() float64
+}
+
+type testCircle struct {
+	Radius float64 `form:"radius"`
+}
+
+func (c testCircle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type testSquare struct {
+	Side float64 `form:"side"`
+}
+
+func (s *testSquare) Area() float64 { return s.Side * s.Side }
+
+func TestDecoder_RegisterInterfaceType(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Shape  testShape   `form:"shape"`
+		Shapes []testShape `form:"shapes"`
+	}
+
+	values := url.Values{
+		"shape._type":      {"circle"},
+		"shape.radius":     {"2"},
+		"shapes[0]._type":  {"square"},
+		"shapes[0].side":   {"3"},
+		"shapes[1]._type":  {"circle"},
+		"shapes[1].radius": {"1"},
+	}
+
+	var tst Test
+
+	d := NewDecoder()
+	err := d.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst.Shape, nil)
+
+	d.RegisterInterfaceType("circle", testCircle{})
+	d.RegisterInterfaceType("square", &testSquare{})
+
+	err = d.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst.Shape, testCircle{Radius: 2})
+	Equal(t, tst.Shapes, []testShape{&testSquare{Side: 3}, testCircle{Radius: 1}})
+
+	e := NewEncoder()
+	e.RegisterInterfaceType("circle", testCircle{})
+	e.RegisterInterfaceType("square", &testSquare{})
+
+	encoded, err := e.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, encoded, values)
+
+	tst = Test{}
+	err = d.Decode(&tst, url.Values{"shape._type": {"triangle"}, "shape.side": {"1"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:shape ERROR:unknown discriminator 'triangle' for interface 'form.testShape' namespace 'shape'")
+
+	err = d.Decode(&tst, url.Values{"shape.side": {"1"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:shape ERROR:missing discriminator '_type' for interface 'form.testShape' namespace 'shape'")
+}
+
+func TestDecoder_SetMaxPathDepth(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name   string                       `form:"name"`
+		Nested map[string]map[string]string `form:"nested"`
+	}
+
+	d := NewDecoder()
+
+	var tst Test
+
+	err := d.Decode(&tst, url.Values{"name": {"a"}, "nested[a][b]": {"c"}})
+	Equal(t, err, nil)
+	Equal(t, tst.Nested, map[string]map[string]string{"a": {"b": "c"}})
+
+	tst = Test{}
+	err = d.Decode(&tst, url.Values{"name": {"a"}, "nested" + strings.Repeat("[a]", 33): {"c"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:nested"+strings.Repeat("[a]", 33)+
+		" ERROR:key path depth of '33' is larger than the maximum currently set on the decoder of '32', "+
+		"see SetMaxPathDepth(depth uint)")
+	Equal(t, tst, Test{})
+
+	d.SetMaxPathDepth(1)
+
+	err = d.Decode(&tst, url.Values{"name": {"a"}, "nested[a][b]": {"c"}})
+	NotEqual(t, err, nil)
+	Equal(t, len(err.(DecodeErrors)), 1)
+	Equal(t, tst, Test{})
+}
+
+func TestDecodeError(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Count int       `form:"count"`
+		Items []uint8   `form:"items"`
+		When  time.Time `form:"when"`
+	}
+
+	var tst Test
+
+	err := NewDecoder().Decode(&tst, url.Values{
+		"count":    {"abc"},
+		"items[0]": {"1"},
+		"items[1]": {"300"},
+		"when":     {"yesterday"},
+	})
+	NotEqual(t, err, nil)
+
+	errs := err.(DecodeErrors)
+	Equal(t, len(errs), 3)
+
+	de := errs["count"].(*DecodeError)
+	Equal(t, de.Namespace, "count")
+	Equal(t, de.Value, "abc")
+	Equal(t, de.Type, reflect.TypeOf(0))
+	Equal(t, de.Error(), "invalid integer value 'abc' type 'int' namespace 'count'")
+
+	de = errs["items[1]"].(*DecodeError)
+	Equal(t, de.Value, "300")
+	Equal(t, de.Type, reflect.TypeOf(uint8(0)))
+
+	var target *DecodeError
+
+	True(t, errors.As(err, &target))
+	Equal(t, target.Namespace, "count")
+
+	var parseErr *time.ParseError
+
+	True(t, errors.As(err, &parseErr))
+	Equal(t, parseErr.Value, "yesterday")
+
+	de = errs["when"].(*DecodeError)
+	Equal(t, de.Type, reflect.TypeOf(time.Time{}))
+	True(t, errors.Is(de, de.Cause))
+}
+
+func TestDecoder_SetLenientKeyParsing(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Items []int          `form:"items"`
+		Map   map[string]int `form:"map"`
+	}
+
+	for _, key := range []string{"items[0", "items]0[", "map]a", "map[a"} {
+		values := url.Values{"items[1]": {"2"}, "map[b]": {"3"}, key: {"1"}}
+
+		var tst Test
+
+		d := NewDecoder()
+		err := d.Decode(&tst, values)
+		NotEqual(t, err, nil, key)
+
+		var warnings []string
+
+		d.SetLenientKeyParsing(true)
+		d.SetWarnFunc(func(namespace, msg string) {
+			warnings = append(warnings, namespace+": "+msg)
+		})
+
+		tst = Test{}
+		err = d.Decode(&tst, values)
+		Equal(t, err, nil, key)
+		Equal(t, tst.Items, []int{0, 2}, key)
+		Equal(t, tst.Map["b"], 3, key)
+		NotEqual(t, len(warnings), 0, key)
+		True(t, strings.HasPrefix(warnings[0], key+": invalid formatting for key '"+key+"'"), warnings[0])
+	}
+}
+
+func TestDecoder_RegisterPattern(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Point  testPoint   `form:"point"`
+		Points []testPoint `form:"points"`
+	}
+
+	encoder := NewEncoder()
+
+	err := encoder.RegisterTemplate(testPoint{}, "{{.Lat}},{{.Lng}}")
+	Equal(t, err, nil)
+
+	decoder := NewDecoder()
+
+	err = decoder.RegisterPattern(testPoint{}, `^(?P<Lat>[^,]+),(?P<Lng>[^,]+)$`)
+	Equal(t, err, nil)
+
+	tst := Test{
+		Point:  testPoint{Lat: 12.3, Lng: 45.6},
+		Points: []testPoint{{Lat: 1, Lng: 2}, {Lat: -3.5, Lng: 4}},
+	}
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	err = decoder.Decode(&decoded, url.Values{"point": {"12.3"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:point ERROR:value '12.3' does not match pattern "+
+		"'^(?P<Lat>[^,]+),(?P<Lng>[^,]+)$' of type 'form.testPoint'")
+
+	err = decoder.Decode(&decoded, url.Values{"point": {"a,1"}})
+	NotEqual(t, err, nil)
+
+	err = decoder.RegisterPattern(testPoint{}, `(?P<Lat>`)
+	NotEqual(t, err, nil)
+
+	// options of the whole input are not applied to pattern groups
+	encoder.SetRootKey("r")
+	decoder.SetRootKey("r")
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+
+	decoded = Test{}
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	sum := func(b []byte) string {
+		return fmt.Sprintf("%x", sha256.Sum256(b))
+	}
+
+	encoder.SetChecksumKey("sum", sum)
+	decoder.SetVerifyChecksum("sum", sum)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+
+	decoded = Test{}
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestDecoder_SetVerifyChecksum(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name string   `form:"name"`
+		Tags []string `form:"tags"`
+	}
+
+	sum := func(b []byte) string {
+		return fmt.Sprintf("%x", sha256.Sum256(b))
+	}
+
+	encoder := NewEncoder()
+	encoder.SetChecksumKey("sum", sum)
+
+	tst := Test{Name: "a b", Tags: []string{"x", "y"}}
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+
+	decoder := NewDecoder()
+	decoder.SetVerifyChecksum("sum", sum)
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	values["tags"] = []string{"x", "z"}
+	decoded = Test{}
+
+	err = decoder.Decode(&decoded, values)
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:sum ERROR:checksum mismatch")
+	Equal(t, decoded, Test{})
+
+	delete(values, "sum")
+
+	err = decoder.Decode(&decoded, values)
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:sum ERROR:missing checksum")
+}
+
+type testColor string
+
+func (c *testColor) UnmarshalText(text []byte) error {
+	switch s := string(text); s {
+	case "red", "green":
+		*c = testColor(s)
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown color %q", text)
+}
+
+func TestDecoder_SetCaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Color  testColor   `form:"color"`
+		Colors []testColor `form:"colors"`
+		Flag   bool        `form:"flag"`
+		Flags  []bool      `form:"flags"`
+	}
+
+	values := url.Values{
+		"color":  {"RED"},
+		"colors": {"Green", "red"},
+		"flag":   {"tRuE"},
+		"flags":  {"YES", "Off", "FALSE"},
+	}
+
+	d := NewDecoder()
+
+	var tst Test
+
+	err := d.Decode(&tst, values)
+	NotEqual(t, err, nil)
+	Equal(t, len(err.(DecodeErrors)), 4)
+
+	d.SetCaseInsensitive(true)
+
+	tst = Test{}
+	err = d.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{
+		Color:  "red",
+		Colors: []testColor{"green", "red"},
+		Flag:   true,
+		Flags:  []bool{true, false, false},
+	})
+
+	err = d.Decode(&tst, url.Values{"color": {"blue"}, "flag": {"maybe"}})
+	NotEqual(t, err, nil)
+	Equal(t, len(err.(DecodeErrors)), 2)
+}
+
+func TestDecoderMapOfSlices(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Tags   map[string][]string `form:"tags"`
+		Matrix map[string][][]int  `form:"matrix"`
+	}
+
+	var tst Test
+
+	d := NewDecoder()
+
+	err := d.Decode(&tst, url.Values{
+		"tags[en][0]":     {"a"},
+		"tags[en][1]":     {"b"},
+		"tags[fr][0]":     {"c"},
+		"tags[de]":        {"d", "e"},
+		"tags[it][2]":     {"f"},
+		"matrix[a][1][2]": {"5"},
+		"matrix[a][0][0]": {"1"},
+		"matrix[b][0]":    {"2", "3"},
+	})
+	Equal(t, err, nil)
+	Equal(t, tst, Test{
+		Tags: map[string][]string{
+			"en": {"a", "b"},
+			"fr": {"c"},
+			"de": {"d", "e"},
+			"it": {"", "", "f"},
+		},
+		Matrix: map[string][][]int{
+			"a": {{1}, {0, 0, 5}},
+			"b": {{2, 3}},
+		},
+	})
+
+	d.SetMaxArraySize(5)
+
+	tst = Test{}
+	err = d.Decode(&tst, url.Values{"tags[en][5]": {"a"}, "matrix[a][1][2]": {"5"}, "matrix[b][6][0]": {"6"}})
+	NotEqual(t, err, nil)
+
+	errs := err.(DecodeErrors)
+	Equal(t, len(errs), 2)
+	Equal(t, errs["tags[en]"].Error(), "array size of '6' is larger than the maximum currently set on the decoder of '5', "+
+		"see SetMaxArraySize(size uint)")
+	Equal(t, errs["matrix[b]"].Error(), "array size of '7' is larger than the maximum currently set on the decoder of '5', "+
+		"see SetMaxArraySize(size uint)")
+	Equal(t, tst.Matrix["a"], [][]int{nil, {0, 0, 5}})
+}
+
+func TestDecoderAmbiguousBracketKeys(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Slice  []string          `form:"slice"`
+		Array  [2]string         `form:"array"`
+		StrMap map[string]string `form:"str_map"`
+		IntMap map[int]string    `form:"int_map"`
+	}
+
+	var tst Test
+
+	d := NewDecoder()
+
+	err := d.Decode(&tst, url.Values{
+		"slice[0]":    {"a"},
+		"slice[2]":    {"c"},
+		"array[1]":    {"b"},
+		"str_map[0]":  {"a"},
+		"str_map[x]":  {"b"},
+		"int_map[0]":  {"a"},
+		"int_map[10]": {"b"},
+	})
+	Equal(t, err, nil)
+	Equal(t, tst, Test{
+		Slice:  []string{"a", "", "c"},
+		Array:  [2]string{"", "b"},
+		StrMap: map[string]string{"0": "a", "x": "b"},
+		IntMap: map[int]string{0: "a", 10: "b"},
+	})
+
+	err = d.Decode(&tst, url.Values{"int_map[x]": {"a"}, "slice[x]": {"b"}})
+	NotEqual(t, err, nil)
+
+	errs := err.(DecodeErrors)
+	Equal(t, errs["int_map"].Error(), "invalid integer value 'x' type 'int' namespace 'int_map'")
+	Equal(t, errs["slice"].Error(), "invalid slice index 'x'")
+}
+
+func TestDecoder_DecodeMerged(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name  string   `form:"name"`
+		Tags  []string `form:"tags"`
+		Page  int      `form:"page"`
+		Token string   `form:"token"`
+	}
+
+	query := url.Values{"name": {"query"}, "tags": {"a", "b", "c"}, "page": {"2"}}
+	cookies := url.Values{"name": {"cookie"}, "tags": {"d"}, "token": {"t"}}
+
+	var tst Test
+
+	err := NewDecoder().DecodeMerged(&tst, query, cookies)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Name: "cookie", Tags: []string{"d"}, Page: 2, Token: "t"})
+
+	tst = Test{}
+	err = NewDecoder().DecodeMerged(&tst, cookies, query)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Name: "query", Tags: []string{"a", "b", "c"}, Page: 2, Token: "t"})
+
+	Equal(t, query["name"], []string{"query"})
+}
+
+func TestDecoder_DecodePopulated(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `form:"city"`
+		Zip  string `form:"zip"`
+	}
+
+	type Test struct {
+		Name    string   `form:"name"`
+		Age     int      `form:"age"`
+		Tags    []string `form:"tags"`
+		Address Address  `form:"address"`
+		Note    *string  `form:"note"`
+	}
+
+	decoder := NewDecoder()
+
+	var tst Test
+
+	populated, err := decoder.DecodePopulated(&tst, url.Values{
+		"age":          {"0"},
+		"tags":         {"a", "b"},
+		"address.city": {"Berlin"},
+		"unknown":      {"x"},
+	})
+	Equal(t, err, nil)
+	Equal(t, populated, []string{"address", "address.city", "age", "tags"})
+	Equal(t, tst.Tags, []string{"a", "b"})
+	Equal(t, tst.Address.City, "Berlin")
+
+	populated, err = decoder.DecodePopulated(&tst, url.Values{})
+	Equal(t, err, nil)
+	Equal(t, populated, []string{})
+
+	populated, err = decoder.DecodePopulated(&tst, url.Values{"age": {"x"}, "name": {"n"}})
+	NotEqual(t, err, nil)
+	Equal(t, populated, []string{"name"})
+
+	_, err = decoder.DecodePopulated(tst, url.Values{})
+	NotEqual(t, err, nil)
+}
+
+func TestDecoder_SetStrictRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Int   int     `form:"int"`
+		Uint  uint8   `form:"uint"`
+		Float float64 `form:"float"`
+		Bool  bool    `form:"bool"`
+		Ints  []int   `form:"ints"`
+		Name  string  `form:"name"`
+	}
+
+	values := url.Values{
+		"int":   {"007"},
+		"uint":  {"05"},
+		"float": {"1.50"},
+		"bool":  {"on"},
+		"ints":  {"1", "02"},
+		"name":  {"007"},
+	}
+
+	decoder := NewDecoder()
+
+	var tst Test
+
+	err := decoder.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Int: 7, Uint: 5, Float: 1.5, Bool: true, Ints: []int{1, 2}, Name: "007"})
+
+	decoder.SetStrictRoundTrip(true)
+
+	err = decoder.Decode(&tst, values)
+	NotEqual(t, err, nil)
+
+	errs := err.(DecodeErrors)
+	Equal(t, len(errs), 5)
+	Equal(t, errs["int"].Error(), "value '007' type 'int' namespace 'int' does not round trip, expected '7'")
+	Equal(t, errs["ints"].(*DecodeError).Value, "02")
+	Equal(t, errs["bool"].(*DecodeError).Value, "on")
+
+	tst = Test{}
+
+	err = decoder.Decode(&tst, url.Values{
+		"int":   {"-7"},
+		"uint":  {"5"},
+		"float": {"1.5"},
+		"bool":  {"false"},
+		"ints":  {"1", "2"},
+	})
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Int: -7, Uint: 5, Float: 1.5, Ints: []int{1, 2}})
+
+	// values accepted by custom and case-insensitive bool parsing are not rejected
+	decoder.SetBoolParseFunc(func(s string) (bool, error) {
+		return s == "Y", nil
+	})
+
+	tst = Test{}
+	err = decoder.Decode(&tst, url.Values{"bool": {"Y"}})
+	Equal(t, err, nil)
+	Equal(t, tst.Bool, true)
+
+	decoder = NewDecoder()
+	decoder.SetStrictRoundTrip(true)
+	decoder.SetCaseInsensitive(true)
+
+	tst = Test{}
+	err = decoder.Decode(&tst, url.Values{"bool": {"TRUE"}})
+	Equal(t, err, nil)
+	Equal(t, tst.Bool, true)
+}
+
+func TestDecoder_SetDefaultsMode(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Limit  int      `form:"limit,default=10"`
+		Sort   string   `form:"sort,default=name"`
+		Ratio  *float64 `form:"ratio,default=0.5"`
+		Offset int      `form:"offset"`
+	}
+
+	half := 0.5
+	values := url.Values{"limit": {""}, "sort": {"date"}}
+
+	var tst Test
+
+	decoder := NewDecoder()
+
+	err := decoder.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Sort: "date"})
+
+	decoder.SetApplyDefaultsFirst(true)
+
+	tst = Test{}
+	err = decoder.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Limit: 10, Sort: "date", Ratio: &half})
+
+	tst = Test{}
+	err = decoder.Decode(&tst, url.Values{"limit": {"x"}})
+	NotEqual(t, err, nil)
+	Equal(t, tst, Test{Limit: 10, Sort: "name", Ratio: &half})
+
+	decoder.SetDefaultsMode(DefaultsAbsent)
+
+	tst = Test{}
+	err = decoder.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Sort: "date", Ratio: &half})
+
+	tst = Test{}
+	err = decoder.Decode(&tst, url.Values{"limit": {"x"}})
+	NotEqual(t, err, nil)
+	Equal(t, tst, Test{Sort: "name", Ratio: &half})
+
+	type Bad struct {
+		Limit int `form:"limit,default=ten"`
+	}
+
+	var bad Bad
+
+	err = decoder.Decode(&bad, url.Values{})
+	NotEqual(t, err, nil)
+	Equal(t, err.(DecodeErrors)["limit"].(*DecodeError).Value, "ten")
+	Equal(t, err.Error(), "Field Namespace:limit ERROR:invalid default value: "+
+		"invalid integer value 'ten' type 'int' namespace 'limit'")
+}
+
+func TestDecoder_SetShapeCoercion(t *testing.T) {
+	t.Parallel()
+
+	type Embedded struct {
+		ID string `form:"id"`
+	}
+
+	type Test struct {
+		Embedded
+		ID   string    `form:"id"`
+		Name string    `form:"name"`
+		Age  *int      `form:"age"`
+		Tags []string  `form:"tags"`
+		Date time.Time `form:"date"`
+	}
+
+	values := url.Values{"name": {"a", "b"}, "age": {"1", "2"}, "tags": {"x"}, "id": {"1", "2"}, "date": {"2020-01-02T00:00:00Z"}}
+
+	decoder := NewDecoder()
+
+	var tst Test
+
+	err := decoder.Decode(&tst, values)
+	NotEqual(t, err, nil)
+
+	errs := err.(DecodeErrors)
+	Equal(t, len(errs), 2)
+	Equal(t, errs["name"].Error(), "2 values for a scalar field of type 'string' namespace 'name', a single value expected")
+	NotEqual(t, errs["age"], nil)
+	Equal(t, tst.Name, "")
+	Equal(t, tst.Tags, []string{"x"})
+	Equal(t, tst.ID, "1")
+
+	var warnings []string
+
+	decoder.SetShapeCoercion(true)
+	decoder.SetWarnFunc(func(namespace, msg string) {
+		warnings = append(warnings, namespace+": "+msg)
+	})
+
+	tst = Test{}
+	err = decoder.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst.Name, "a")
+	Equal(t, *tst.Age, 1)
+	Equal(t, tst.Tags, []string{"x"})
+
+	Equal(t, warnings, []string{
+		"name: 2 values for a scalar field, using the first one",
+		"age: 2 values for a scalar field, using the first one",
+		"tags: single value is decoded as a one-element slice",
+	})
+
+	// fields of embedded structs are checked unless they share keys with fields of embedding struct
+	type Inner struct {
+		Count int `form:"count"`
+	}
+
+	type Base struct {
+		Embedded
+		Foo   string `form:"foo"`
+		Inner Inner  `form:"inner"`
+	}
+
+	type WithBase struct {
+		Base
+		ID string `form:"id"`
+	}
+
+	var wb WithBase
+
+	err = NewDecoder().Decode(&wb, url.Values{"foo": {"a", "b"}, "inner.count": {"1", "2"}, "id": {"1", "2"}})
+	NotEqual(t, err, nil)
+
+	errs = err.(DecodeErrors)
+	Equal(t, len(errs), 2)
+	NotEqual(t, errs["foo"], nil)
+	NotEqual(t, errs["inner.count"], nil)
+	Equal(t, wb.ID, "1")
+	Equal(t, wb.Base.ID, "1")
+}
+
+func TestDecoder_SetMaxTotalValueBytes(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name string   `form:"name"`
+		Tags []string `form:"tags"`
+	}
+
+	values := url.Values{"name": {"abcd"}, "tags": {"ef", "gh"}, "other": {"ij"}}
+
+	decoder := NewDecoder()
+	decoder.SetMaxTotalValueBytes(10)
+
+	var tst Test
+
+	err := decoder.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Name: "abcd", Tags: []string{"ef", "gh"}})
+
+	// unbound values of "other" are not counted
+	decoder.SetMaxTotalValueBytes(8)
+
+	tst = Test{}
+	err = decoder.Decode(&tst, values)
+	Equal(t, err, nil)
+	Equal(t, tst, Test{Name: "abcd", Tags: []string{"ef", "gh"}})
+
+	decoder.SetMaxTotalValueBytes(7)
+
+	tst = Test{}
+	err = decoder.Decode(&tst, values)
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace: ERROR:total length of bound values of '8' bytes is larger than "+
+		"the maximum currently set on the decoder of '7', see SetMaxTotalValueBytes(n int)")
+	Equal(t, tst, Test{Name: "abcd"})
+}
+
+func TestDecoder_SetMaxAllocDepth(t *testing.T) {
+	t.Parallel()
+
+	type Node struct {
+		ID   int   `form:"id"`
+		Next *Node `form:"next"`
+	}
+
+	decoder := NewDecoder()
+	decoder.SetMaxAllocDepth(2)
+
+	var n Node
+
+	err := decoder.Decode(&n, url.Values{"id": {"1"}, "next.id": {"2"}, "next.next.id": {"3"}})
+	Equal(t, err, nil)
+	Equal(t, n.Next.Next.ID, 3)
+	Equal(t, n.Next.Next.Next, (*Node)(nil))
+
+	n = Node{}
+
+	err = decoder.Decode(&n, url.Values{"id": {"1"}, "next.next.next.next.id": {"5"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:next.next.next ERROR:struct pointer allocation depth of '3' is larger than "+
+		"the maximum currently set on the decoder of '2', see SetMaxAllocDepth(n int)")
+	Equal(t, n.ID, 1)
+	Equal(t, n.Next, (*Node)(nil))
+
+	// without the limit deep keys are allocated
+	n = Node{}
+
+	err = NewDecoder().Decode(&n, url.Values{"next.next.next.next.id": {"5"}})
+	Equal(t, err, nil)
+	Equal(t, n.Next.Next.Next.Next.ID, 5)
+}
@@ -254,6 +254,26 @@ you can tell form to omit empty fields using `,omitempty` or `FieldName,omitempt
 	    Field2 string `form:"CustomFieldName,omitempty"`
 	}
 
+# Tag Options
+
+other options follow the name in the tag, separated with commas, e.g. `form:"tags,max=10,count=tags_count"`
+
+  - omitempty - omit field with zero value when encoding.
+  - omitdefault - omit field that deeply equals to zero value of its type when encoding.
+  - noescape - emit value without URL escaping with Encoder.EncodeToWriter, value must be trusted.
+  - required - fail encoding of empty field, see Encoder.SetRequiredMode.
+  - default=value - value to decode if key is absent, see Decoder.SetDefaultsMode.
+  - max=n - maximum number of slice or array items to encode, see Encoder.SetSliceLimitMode.
+  - count=key - emit length of slice or array field under the sibling key.
+  - split=date:time - encode and decode time.Time field as two keys with suffixes, e.g. "at_date" and "at_time".
+  - jsonarray - encode and decode slice or array field as a single JSON array value.
+  - emptyas=key - emit marker key for empty non-nil slice, so that it is decoded as empty slice and not nil.
+  - keycase=lower or keycase=upper - force case of the key, it applies after all other name transforms.
+  - transform=name - encode value with function registered with Encoder.RegisterTransform.
+  - group=a|b - encode field only for listed groups with Encoder.EncodeForGroups.
+  - visibility=level - encode field only if it is visible at level of Encoder.SetVisibility.
+  - encrypt - encrypt values of field, including nested ones, see Encoder.SetEncryptFunc and Decoder.SetDecryptFunc.
+
 # Notes
 
 To maximize compatibility with other systems the Encoder attempts
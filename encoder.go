@@ -1,24 +1,54 @@
 package form
 
 import (
+	"bufio"
 	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const errRequired = "required field is empty"
+
 type encoder struct {
 	e         *Encoder
 	errs      EncodeErrors
 	columns   []string
+	colsBuf   []string
 	values    url.Values
 	goValues  map[string]interface{}
+	rawKeys   map[string]struct{}
+	pointers  map[uintptr]string
+	noEscape  bool
+	encrypt   bool
+	sparse    bool
+	mask      reflect.Value
+	groups    []string
+	depth     int
 	namespace []byte
 }
 
+func (e *encoder) reset() {
+	e.errs = nil
+	e.columns = nil
+	e.values = nil
+	e.goValues = nil
+	e.rawKeys = nil
+	e.pointers = nil
+	e.noEscape = false
+	e.encrypt = false
+	e.sparse = false
+	e.mask = reflect.Value{}
+	e.groups = nil
+}
+
 func (e *encoder) setError(namespace []byte, err error) {
 	if e.errs == nil {
 		e.errs = make(EncodeErrors)
@@ -27,22 +57,279 @@ func (e *encoder) setError(namespace []byte, err error) {
 	e.errs[string(namespace)] = err
 }
 
+func (e *encoder) warn(namespace []byte, msg string) {
+	if e.e.warnFunc != nil {
+		e.e.warnFunc(string(namespace), msg)
+	}
+}
+
 func (e *encoder) setVal(namespace []byte, v reflect.Value, vals ...string) {
+	if e.e.skipFunc != nil && e.e.skipFunc(string(namespace), v) {
+		return
+	}
+
+	if e.encrypt {
+		var ok bool
+
+		if vals, ok = e.encryptValues(namespace, vals); !ok {
+			return
+		}
+	}
+
 	if e.goValues != nil {
 		e.goValues[string(namespace)] = v.Interface()
 	}
 
-	arr, ok := e.values[string(namespace)]
+	if e.noEscape {
+		if e.rawKeys == nil {
+			e.rawKeys = make(map[string]struct{})
+		}
+
+		e.rawKeys[string(namespace)] = struct{}{}
+	}
+
+	// key string is allocated once for both values and columns
+	key := string(namespace)
+
+	arr, ok := e.values[key]
 	if ok {
 		arr = append(arr, vals...)
 	} else {
 		if e.columns != nil {
-			e.columns = append(e.columns, string(namespace))
+			e.columns = append(e.columns, key)
 		}
 		arr = vals
 	}
 
-	e.values[string(namespace)] = arr
+	e.values[key] = arr
+}
+
+// encryptValues returns values encrypted with encrypt func, or false if encryption fails.
+func (e *encoder) encryptValues(namespace []byte, vals []string) ([]string, bool) {
+	if e.e.encryptFunc == nil {
+		e.setError(namespace, fmt.Errorf("missing encrypt func for field with encrypt tag option namespace '%s'", namespace))
+
+		return nil, false
+	}
+
+	encrypted := make([]string, len(vals))
+
+	for i, s := range vals {
+		c, err := e.e.encryptFunc(s)
+		if err != nil {
+			e.setError(namespace, err)
+
+			return nil, false
+		}
+
+		encrypted[i] = c
+	}
+
+	return encrypted, true
+}
+
+func (e *encoder) encode(v interface{}) error {
+	val, kind := ExtractType(reflect.ValueOf(v))
+
+	if kind == reflect.Ptr || kind == reflect.Interface || kind == reflect.Invalid {
+		return &InvalidEncodeError{Type: reflect.TypeOf(v)}
+	}
+
+	e.values = make(url.Values)
+
+	// emission order of keys is needed to sequence them
+	if e.e.sequenceWidth > 0 && e.columns == nil {
+		e.columns = make([]string, 0)
+	}
+
+	namespace := append(e.namespace[0:0], e.e.rootKey...)
+
+	tm, isMarshaler := v.(encoding.TextMarshaler)
+	if !isMarshaler {
+		tm, isMarshaler = val.Interface().(encoding.TextMarshaler)
+	}
+
+	_, isFielder := val.Interface().(FormFielder)
+
+	switch {
+	case isFielder:
+		e.setFieldByType(val, namespace, -1, cachedField{})
+	case isMarshaler && !isTimeType(val.Type()):
+		// top level value is encoded as a whole under base namespace
+		if b, err := tm.MarshalText(); err != nil {
+			e.setError(namespace, err)
+		} else {
+			e.setVal(namespace, val, string(b))
+		}
+	case kind == reflect.Struct && !isTimeType(val.Type()):
+		e.traverseStruct(val, namespace, -1)
+	default:
+		e.setFieldByType(val, namespace, -1, cachedField{})
+	}
+
+	if len(e.errs) > 0 {
+		return e.errs
+	}
+
+	if e.e.fieldPriority != nil && e.columns != nil {
+		e.sortColumns()
+	}
+
+	if e.e.debugComments {
+		e.setDebugPath(val.Type())
+	}
+
+	if e.e.versionKey != "" {
+		e.setKey(e.e.versionKey, e.e.version)
+	}
+
+	if e.e.idempotencyFunc != nil {
+		e.setKey(e.e.idempotencyKey, e.e.idempotencyFunc())
+	}
+
+	if e.e.checksumFunc != nil {
+		e.setChecksum()
+	}
+
+	if e.e.sequenceWidth > 0 {
+		e.setSequence()
+	}
+
+	return nil
+}
+
+// setSequence prefixes keys with sequence number in emission order, e.g. "001:field".
+func (e *encoder) setSequence() {
+	values := make(url.Values, len(e.values))
+
+	for i, k := range e.columns {
+		sk := fmt.Sprintf("%0*d:%s", e.e.sequenceWidth, i+1, k)
+		values[sk] = e.values[k]
+		e.columns[i] = sk
+
+		if _, ok := e.rawKeys[k]; ok {
+			e.rawKeys[sk] = struct{}{}
+		}
+	}
+
+	e.values = values
+}
+
+// setChecksum adds checksum of canonical encoded values, that are sorted by key, under checksum key.
+func (e *encoder) setChecksum() {
+	sums, exists := e.values[e.e.checksumKey]
+	delete(e.values, e.e.checksumKey)
+
+	sum := e.e.checksumFunc([]byte(e.values.Encode()))
+
+	// restored to keep position in columns
+	if exists {
+		e.values[e.e.checksumKey] = sums
+	}
+
+	e.setKey(e.e.checksumKey, sum)
+}
+
+// setKey sets a single value of the key replacing values encoded from fields.
+func (e *encoder) setKey(key, value string) {
+	if _, exists := e.values[key]; !exists && e.columns != nil {
+		e.columns = append(e.columns, key)
+	}
+
+	e.values[key] = []string{value}
+}
+
+// setDebugPath sets no-op debug key with encoded type before other keys.
+func (e *encoder) setDebugPath(t reflect.Type) {
+	if _, exists := e.values[debugPathKey]; !exists && e.columns != nil {
+		e.columns = append(e.columns, "")
+		copy(e.columns[1:], e.columns)
+		e.columns[0] = debugPathKey
+	}
+
+	e.values[debugPathKey] = []string{t.String()}
+}
+
+// sortColumns orders columns by field priority, lower first, keeping order of encoding for ties.
+func (e *encoder) sortColumns() {
+	priority := func(col string) int {
+		if p, ok := e.e.fieldPriority[col]; ok {
+			return p
+		}
+
+		if i := strings.IndexAny(col, ".["); i > 0 {
+			return e.e.fieldPriority[col[:i]]
+		}
+
+		return 0
+	}
+
+	sort.SliceStable(e.columns, func(i, j int) bool {
+		return priority(e.columns[i]) < priority(e.columns[j])
+	})
+}
+
+// writeTo writes encoded values in URL-encoded form following the order of columns.
+func (e *encoder) writeTo(w io.Writer) error {
+	sw, ok := w.(io.StringWriter)
+	if !ok {
+		bw := bufio.NewWriter(w)
+		if err := e.writeStrings(bw); err != nil {
+			return err
+		}
+
+		return bw.Flush()
+	}
+
+	return e.writeStrings(sw)
+}
+
+// reusedColumns returns empty columns backed by buffer of previous use.
+func (e *encoder) reusedColumns() []string {
+	if e.colsBuf == nil {
+		return make([]string, 0)
+	}
+
+	return e.colsBuf[:0]
+}
+
+// encodedLen returns estimated length of encoded values, that is exact unless escaping is needed.
+func (e *encoder) encodedLen() int {
+	n := 0
+
+	for k, vals := range e.values {
+		for _, v := range vals {
+			n += len(k) + len(v) + 2
+		}
+	}
+
+	return n
+}
+
+func (e *encoder) writeStrings(sw io.StringWriter) error {
+	sep := ""
+
+	for _, k := range e.columns {
+		_, raw := e.rawKeys[k]
+		ek := url.QueryEscape(k)
+
+		for _, v := range e.values[k] {
+			if !raw {
+				v = url.QueryEscape(v)
+			}
+
+			// pieces are written separately to avoid allocation of concatenated string
+			for _, s := range [4]string{sep, ek, "=", v} {
+				if _, err := sw.WriteString(s); err != nil {
+					return err
+				}
+			}
+
+			sep = "&"
+		}
+	}
+
+	return nil
 }
 
 func (e *encoder) traverseStruct(v reflect.Value, namespace []byte, idx int) {
@@ -57,9 +344,56 @@ func (e *encoder) traverseStruct(v reflect.Value, namespace []byte, idx int) {
 		s = e.e.structCache.parseStruct(e.e.mode, typ, e.e.tagName)
 	}
 
-	for _, f := range s.fields {
+	var namer FieldNamer
+
+	if v.CanInterface() {
+		namer, _ = v.Interface().(FieldNamer)
+	}
+
+	e.depth++
+	mask := e.mask
+
+	defer func() {
+		e.depth--
+		e.mask = mask
+	}()
+
+	for i := range s.fields {
+		f := s.fields[i]
+
+		if e.e.reverseFields {
+			f = s.fields[len(s.fields)-1-i]
+		}
+
 		namespace = namespace[:l]
 
+		if mask.IsValid() {
+			m, ok := fieldMask(mask, typ.Field(f.idx).Name)
+			if !ok {
+				continue
+			}
+
+			e.mask = m
+		}
+
+		if e.groups != nil && f.groups != nil {
+			if _, ok := matchGroup(f.groups, e.groups); !ok {
+				continue
+			}
+		}
+
+		if e.e.visibility != "" && f.visibility != "" && !e.e.isVisible(f.visibility) {
+			continue
+		}
+
+		if omit, ok := e.e.omitEmptyPolicy[v.Field(f.idx).Kind()]; ok {
+			f.isOmitEmpty = omit
+		}
+
+		if e.sparse {
+			f.isOmitEmpty = true
+		}
+
 		if f.isAnonymous && e.e.embedAnonymous {
 			if f.hasExportedScalar {
 				e.setFieldByType(v.Field(f.idx), namespace, idx, f)
@@ -68,15 +402,49 @@ func (e *encoder) traverseStruct(v reflect.Value, namespace []byte, idx int) {
 			continue
 		}
 
+		name := f.name
+
+		if namer != nil {
+			if n := namer.FormFieldName(typ.Field(f.idx).Name); n != "" {
+				name = n
+			}
+		}
+
+		name = applyKeyCase(sliceKey(name, typ.Field(f.idx).Type, e.e.pluralize), f.keyCase)
+
 		if first {
-			namespace = append(namespace, f.name...)
+			namespace = append(namespace, name...)
 		} else {
 			namespace = append(namespace, namespaceSeparator)
-			namespace = append(namespace, f.name...)
+			namespace = append(namespace, name...)
 		}
 
+		if f.splitSuffixes != nil {
+			e.setSplitTime(v.Field(f.idx), namespace, f)
+
+			continue
+		}
+
+		if f.emptyAs != "" {
+			if fv := reflect.Indirect(v.Field(f.idx)); fv.Kind() == reflect.Slice && !fv.IsNil() && fv.Len() == 0 {
+				e.setVal(append(namespace[:len(namespace)-len(name)], f.emptyAs...), fv, "")
+
+				continue
+			}
+		}
+
+		noEscape, encrypt := e.noEscape, e.encrypt
+		e.noEscape = noEscape || f.isNoEscape
+		e.encrypt = encrypt || f.isEncrypted
+
 		e.setFieldByType(v.Field(f.idx), namespace, idx, f)
 
+		e.noEscape, e.encrypt = noEscape, encrypt
+
+		if f.countKey != "" {
+			e.setCount(v.Field(f.idx), namespace[:l], f)
+		}
+
 		if f.sliceSeparator != 0 {
 			ns := string(namespace)
 			if len(e.values[ns]) > 0 {
@@ -87,19 +455,140 @@ func (e *encoder) traverseStruct(v reflect.Value, namespace []byte, idx int) {
 }
 
 func (e *encoder) setFieldByType(current reflect.Value, namespace []byte, idx int, f cachedField) {
+	// pointer chains, e.g. **T, are collapsed to the first nil or the last pointer,
+	// so that they are encoded the same as a single pointer
+	for current.Kind() == reflect.Ptr && !current.IsNil() && current.Elem().Kind() == reflect.Ptr {
+		current = current.Elem()
+	}
+
 	if idx > -1 && current.Kind() == reflect.Ptr {
 		namespace = append(namespace, '[')
 		namespace = strconv.AppendInt(namespace, int64(idx), 10)
 		namespace = append(namespace, ']')
 		idx = -2
+
+		if e.setNilElement(current, namespace) {
+			return
+		}
+	}
+
+	if e.e.interfaceTypes != nil && current.Kind() == reflect.Interface && !current.IsNil() {
+		if name, ok := e.e.interfaceTypes[current.Elem().Type()]; ok {
+			e.setDiscriminated(current.Elem(), namespace, idx, name)
+
+			return
+		}
+	}
+
+	if e.e.autoDiscriminator && current.Kind() == reflect.Interface && !current.IsNil() {
+		if t := derefType(current.Elem().Type()); t.Kind() == reflect.Struct && t.Name() != "" && !isTimeType(t) {
+			e.setDiscriminated(current.Elem(), namespace, idx, t.Name())
+
+			return
+		}
+	}
+
+	if e.e.sharedPtrMode == SharedPointerReference && current.Kind() == reflect.Ptr && !current.IsNil() &&
+		current.Elem().Kind() == reflect.Struct {
+		if ns, ok := e.pointers[current.Pointer()]; ok {
+			e.setVal(namespace, current, sharedPointerRef+ns)
+
+			return
+		}
+
+		if e.pointers == nil {
+			e.pointers = make(map[uintptr]string)
+		}
+
+		e.pointers[current.Pointer()] = string(namespace)
+	}
+
+	if e.e.zeroTime != nil {
+		if v, _ := ExtractType(current); v.IsValid() && isTimeType(v.Type()) && timeValue(v).Interface().(time.Time).IsZero() {
+			if idx > -1 {
+				namespace = append(namespace, '[')
+				namespace = strconv.AppendInt(namespace, int64(idx), 10)
+				namespace = append(namespace, ']')
+			}
+
+			e.setVal(namespace, v, *e.e.zeroTime)
+
+			return
+		}
+	}
+
+	if f.isRequired && !hasValue(current) {
+		switch e.e.requiredMode {
+		case RequiredError:
+			e.setError(namespace, errors.New(e.requiredMessage(f)))
+
+			return
+		case RequiredPlaceholder:
+			e.setVal(namespace, current, e.e.requiredValue)
+
+			return
+		case RequiredWarn:
+			e.warn(namespace, e.requiredMessage(f))
+		}
 	}
 
 	if f.isOmitEmpty && !hasValue(current) {
 		return
 	}
 
+	if f.isOmitDefault && isDefault(current) {
+		return
+	}
+
+	if e.e.omitDefaults && f.defaultValue != nil && equalsDefault(current, *f.defaultValue) {
+		return
+	}
+
+	if e.e.nilStruct != "" && len(namespace) > 0 && current.Kind() == reflect.Ptr && current.IsNil() {
+		if t := derefType(current.Type()); t.Kind() == reflect.Struct && !isTimeType(t) {
+			e.setVal(namespace, current, e.e.nilStruct)
+
+			return
+		}
+	}
+
 	v, kind := ExtractType(current)
 
+	if f.unknownTransform != "" {
+		e.setError(namespace, fmt.Errorf("unknown transform '%s' namespace '%s'", f.unknownTransform, namespace))
+
+		return
+	}
+
+	if f.transform != nil && kind != reflect.Ptr {
+		s, err := f.transform(v)
+		if err != nil {
+			e.setError(namespace, err)
+
+			return
+		}
+
+		e.setVal(namespace, v, s)
+
+		return
+	}
+
+	if kind == reflect.Struct && v.CanInterface() {
+		if o, ok := v.Interface().(optionalEncoder); ok {
+			set, null, value := o.optionalState()
+
+			switch {
+			case !set:
+			case null:
+				e.setVal(namespace, v, "")
+			default:
+				e.setFieldByType(reflect.ValueOf(value), namespace, idx, f)
+			}
+
+			return
+		}
+	}
+
 	if e.e.customTypeFuncs != nil {
 		if cf, ok := e.e.customTypeFuncs[v.Type()]; ok {
 			val, err := cf(v.Interface())
@@ -121,6 +610,32 @@ func (e *encoder) setFieldByType(current reflect.Value, namespace []byte, idx in
 		}
 	}
 
+	if e.e.timeLayout != "" && kind == reflect.Struct && isTimeType(v.Type()) {
+		if idx > -1 {
+			namespace = append(namespace, '[')
+			namespace = strconv.AppendInt(namespace, int64(idx), 10)
+			namespace = append(namespace, ']')
+		}
+
+		e.setVal(namespace, v, timeValue(v).Interface().(time.Time).Format(e.e.timeLayout))
+
+		return
+	}
+
+	if !(kind == reflect.Ptr && v.IsNil()) && v.CanInterface() {
+		if ff, ok := v.Interface().(FormFielder); ok {
+			if idx > -1 {
+				namespace = append(namespace, '[')
+				namespace = strconv.AppendInt(namespace, int64(idx), 10)
+				namespace = append(namespace, ']')
+			}
+
+			e.setFormFields(namespace, ff.FormFields())
+
+			return
+		}
+	}
+
 	if f.isExported && len(namespace) > 0 && !(kind == reflect.Ptr && v.IsNil()) {
 		if tu, ok := v.Interface().(encoding.TextMarshaler); ok {
 			val, err := tu.MarshalText()
@@ -141,11 +656,35 @@ func (e *encoder) setFieldByType(current reflect.Value, namespace []byte, idx in
 		}
 	}
 
+	if e.e.useJSONMarshaler && !(kind == reflect.Ptr && v.IsNil()) && v.CanInterface() {
+		if jm, ok := v.Interface().(json.Marshaler); ok {
+			if _, isText := v.Interface().(encoding.TextMarshaler); !isText {
+				e.setJSON(jm, namespace, idx)
+
+				return
+			}
+		}
+	}
+
+	if e.e.numberLocale != nil {
+		if f, ok := numberValue(v); ok {
+			e.setVal(namespace, v, e.e.numberLocale(f))
+
+			return
+		}
+	}
+
 	switch kind {
 	case reflect.Ptr, reflect.Interface, reflect.Invalid:
 		return
 
 	case reflect.String:
+		if e.e.nullString != nil && v.Len() == 0 {
+			e.setVal(namespace, v, *e.e.nullString)
+
+			return
+		}
+
 		e.setVal(namespace, v, v.String())
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
@@ -161,30 +700,90 @@ func (e *encoder) setFieldByType(current reflect.Value, namespace []byte, idx in
 		e.setVal(namespace, v, strconv.FormatFloat(v.Float(), 'f', -1, 64))
 
 	case reflect.Bool:
-		e.setVal(namespace, v, strconv.FormatBool(v.Bool()))
+		e.setVal(namespace, v, e.formatBool(v.Bool()))
 
 	case reflect.Slice, reflect.Array:
-		if idx == -1 {
-			for i := 0; i < v.Len(); i++ {
-				e.setFieldByType(v.Index(i), namespace, i, cachedField{})
+		if f.isJSONArray {
+			if v.Kind() == reflect.Slice && v.IsNil() {
+				return
 			}
 
+			b, err := json.Marshal(v.Interface())
+			if err != nil {
+				e.setError(namespace, err)
+
+				return
+			}
+
+			e.setVal(namespace, v, string(b))
+
 			return
 		}
 
+		if e.e.dedupeStructs {
+			v = dedupeStructs(v)
+		}
+
+		n := v.Len()
+
+		if f.maxItems > 0 && n > f.maxItems {
+			if e.e.sliceLimitMode == SliceLimitError {
+				e.setError(namespace, fmt.Errorf("length %d exceeds maximum of %d items", n, f.maxItems))
+
+				return
+			}
+
+			n = f.maxItems
+		}
+
+		if e.e.keyFuncs != nil {
+			if kf, ok := e.e.keyFuncs[derefType(v.Type().Elem())]; ok {
+				if idx > -1 {
+					namespace = append(namespace, '[')
+					namespace = strconv.AppendInt(namespace, int64(idx), 10)
+					namespace = append(namespace, ']')
+				}
+
+				e.setKeyedItems(v, n, namespace, kf)
+
+				return
+			}
+		}
+
 		if idx > -1 {
 			namespace = append(namespace, '[')
 			namespace = strconv.AppendInt(namespace, int64(idx), 10)
 			namespace = append(namespace, ']')
 		}
 
+		if e.e.indexStyle == IndexStyleRepeatedParent && isStructType(v.Type().Elem()) {
+			for i := 0; i < n; i++ {
+				e.setFieldByType(v.Index(i), namespace, -2, cachedField{})
+			}
+
+			return
+		}
+
+		if e.repeatItems(v, idx) {
+			for i := 0; i < n; i++ {
+				e.setFieldByType(v.Index(i), namespace, i, cachedField{})
+			}
+
+			return
+		}
+
 		namespace = append(namespace, '[')
 		l := len(namespace)
 
-		for i := 0; i < v.Len(); i++ {
+		for i := 0; i < n; i++ {
 			namespace = namespace[:l]
 			namespace = strconv.AppendInt(namespace, int64(i), 10)
 			namespace = append(namespace, ']')
+
+			if e.setNilElement(v.Index(i), namespace) {
+				continue
+			}
+
 			e.setFieldByType(v.Index(i), namespace, -2, cachedField{})
 		}
 
@@ -195,6 +794,15 @@ func (e *encoder) setFieldByType(current reflect.Value, namespace []byte, idx in
 			namespace = append(namespace, ']')
 		}
 
+		if e.e.flattenSingle && v.Len() == 1 {
+			iter := v.MapRange()
+			iter.Next()
+
+			e.setFieldByType(iter.Value(), namespace, -2, cachedField{})
+
+			return
+		}
+
 		var (
 			valid bool
 			s     string
@@ -209,6 +817,14 @@ func (e *encoder) setFieldByType(current reflect.Value, namespace []byte, idx in
 				continue
 			}
 
+			if e.e.mapKeyTransform != nil && v.Type().Key().Kind() == reflect.String {
+				s = e.e.mapKeyTransform(s)
+			}
+
+			if e.e.escapeMapKeys {
+				s = escapeMapKey(s)
+			}
+
 			namespace = append(namespace, '[')
 			namespace = append(namespace, s...)
 			namespace = append(namespace, ']')
@@ -218,14 +834,39 @@ func (e *encoder) setFieldByType(current reflect.Value, namespace []byte, idx in
 
 	case reflect.Struct:
 		// if we get here then no custom time function declared so use RFC3339 by default
-		if v.Type() == timeType {
+		if isTimeType(v.Type()) {
 			if idx > -1 {
 				namespace = append(namespace, '[')
 				namespace = strconv.AppendInt(namespace, int64(idx), 10)
 				namespace = append(namespace, ']')
 			}
 
-			e.setVal(namespace, v, v.Interface().(time.Time).Format(time.RFC3339))
+			e.setVal(namespace, v, timeValue(v).Interface().(time.Time).Format(time.RFC3339))
+
+			return
+		}
+
+		if idx > -1 {
+			namespace = append(namespace, '[')
+			namespace = strconv.AppendInt(namespace, int64(idx), 10)
+			namespace = append(namespace, ']')
+		}
+
+		if e.e.emptyStruct != "" && len(namespace) > 0 && v.IsZero() {
+			e.setVal(namespace, v, e.e.emptyStruct)
+
+			return
+		}
+
+		if e.e.jsonBelowDepth > 0 && e.depth > e.e.jsonBelowDepth && v.CanInterface() {
+			b, err := json.Marshal(v.Interface())
+			if err != nil {
+				e.setError(namespace, err)
+
+				return
+			}
+
+			e.setVal(namespace, v, string(b))
 
 			return
 		}
@@ -236,17 +877,253 @@ func (e *encoder) setFieldByType(current reflect.Value, namespace []byte, idx in
 			return
 		}
 
-		if idx > -1 {
-			namespace = append(namespace, '[')
-			namespace = strconv.AppendInt(namespace, int64(idx), 10)
-			namespace = append(namespace, ']')
+		e.traverseStruct(v, namespace, -2)
+	}
+}
+
+// setDiscriminated sets a value held by interface along with its type discriminator.
+func (e *encoder) setDiscriminated(v reflect.Value, namespace []byte, idx int, name string) {
+	if idx > -1 {
+		namespace = append(namespace, '[')
+		namespace = strconv.AppendInt(namespace, int64(idx), 10)
+		namespace = append(namespace, ']')
+	}
+
+	l := len(namespace)
+
+	if l > 0 {
+		namespace = append(namespace, namespaceSeparator)
+	}
+
+	namespace = append(namespace, discriminatorKey...)
+	e.setVal(namespace, reflect.ValueOf(name), name)
+
+	e.setFieldByType(v, namespace[:l], -2, cachedField{})
+}
+
+// fieldMask returns mask of a field by name from mask struct and reports whether the field is enabled,
+// mask is invalid if the field is entirely enabled with true bool.
+func fieldMask(mask reflect.Value, name string) (reflect.Value, bool) {
+	m, kind := ExtractType(mask.FieldByName(name))
+
+	switch kind {
+	case reflect.Bool:
+		return reflect.Value{}, m.Bool()
+	case reflect.Struct:
+		return m, true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// requiredMessage returns message for empty required field, scoped to a group when encoding for groups.
+func (e *encoder) requiredMessage(f cachedField) string {
+	if e.groups != nil && f.groups != nil {
+		if g, ok := matchGroup(f.groups, e.groups); ok {
+			return errRequired + " in group '" + g + "'"
 		}
+	}
 
-		e.traverseStruct(v, namespace, -2)
+	return errRequired
+}
+
+// matchGroup returns first group of field groups that is requested.
+func matchGroup(fieldGroups, groups []string) (string, bool) {
+	for _, fg := range fieldGroups {
+		for _, g := range groups {
+			if fg == g {
+				return fg, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// setJSON sets a single value of MarshalJSON result.
+func (e *encoder) setJSON(jm json.Marshaler, namespace []byte, idx int) {
+	b, err := jm.MarshalJSON()
+	if err != nil {
+		e.setError(namespace, err)
+
+		return
+	}
+
+	if idx > -1 {
+		namespace = append(namespace, '[')
+		namespace = strconv.AppendInt(namespace, int64(idx), 10)
+		namespace = append(namespace, ']')
+	}
+
+	e.setVal(namespace, reflect.ValueOf(jm), string(b))
+}
+
+// setNilElement sets placeholder for nil pointer element of slice and reports whether it was set.
+func (e *encoder) setNilElement(item reflect.Value, namespace []byte) bool {
+	if e.e.nilElement == nil || item.Kind() != reflect.Ptr || !item.IsNil() {
+		return false
+	}
+
+	e.setVal(namespace, item, *e.e.nilElement)
+
+	return true
+}
+
+// dedupeStructs returns slice of unique struct elements of v in order of first occurrence,
+// elements are compared with reflect.DeepEqual.
+func dedupeStructs(v reflect.Value) reflect.Value {
+	if t := derefType(v.Type().Elem()); t.Kind() != reflect.Struct || isTimeType(t) || v.Len() < 2 || !v.CanInterface() {
+		return v
+	}
+
+	unique := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), 0, v.Len())
+
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i).Interface()
+		seen := false
+
+		for j := 0; j < unique.Len(); j++ {
+			if reflect.DeepEqual(unique.Index(j).Interface(), item) {
+				seen = true
+
+				break
+			}
+		}
+
+		if !seen {
+			unique = reflect.Append(unique, v.Index(i))
+		}
+	}
+
+	return unique
+}
+
+// repeatItems reports whether slice elements should share the key of slice.
+func (e *encoder) repeatItems(v reflect.Value, idx int) bool {
+	// elements of interface slice are always indexed to keep positions of mixed dynamic types.
+	if v.Type().Elem().Kind() == reflect.Interface {
+		return false
+	}
+
+	switch e.e.indexStyle {
+	case IndexStyleIndexed:
+		return false
+	case IndexStyleRepeated:
+		return true
+	default:
+		return idx == -1
+	}
+}
+
+func (e *encoder) formatBool(b bool) string {
+	if e.e.boolFunc != nil {
+		return e.e.boolFunc(b)
+	}
+
+	return strconv.FormatBool(b)
+}
+
+// setSplitTime sets date and time parts of time value with namespace suffixes of split option.
+func (e *encoder) setSplitTime(current reflect.Value, namespace []byte, f cachedField) {
+	v, kind := ExtractType(current)
+	if kind != reflect.Struct || (f.isOmitEmpty && v.IsZero()) {
+		return
+	}
+
+	t := v.Interface().(time.Time) //nolint:errcheck // Type is checked in struct cache.
+	l := len(namespace)
+
+	namespace = append(namespace, '_')
+	namespace = append(namespace, f.splitSuffixes[0]...)
+	e.setVal(namespace, v, t.Format(splitDateLayout))
+
+	namespace = append(namespace[:l], '_')
+	namespace = append(namespace, f.splitSuffixes[1]...)
+	e.setVal(namespace, v, t.Format(splitTimeLayout))
+}
+
+// setCount sets length of a slice or an array field under the count key of the field.
+func (e *encoder) setCount(current reflect.Value, namespace []byte, f cachedField) {
+	v, kind := ExtractType(current)
+	if kind != reflect.Slice && kind != reflect.Array {
+		return
+	}
+
+	n := v.Len()
+	if f.maxItems > 0 && n > f.maxItems {
+		n = f.maxItems
+	}
+
+	if len(namespace) > 0 {
+		namespace = append(namespace, namespaceSeparator)
+	}
+
+	namespace = append(namespace, f.countKey...)
+	e.setVal(namespace, reflect.ValueOf(n), strconv.Itoa(n))
+}
+
+func (e *encoder) setKeyedItems(v reflect.Value, n int, namespace []byte, kf KeyFunc) {
+	l := len(namespace)
+
+	for i := 0; i < n; i++ {
+		item, kind := ExtractType(v.Index(i))
+		if kind == reflect.Ptr || kind == reflect.Interface {
+			continue
+		}
+
+		namespace = namespace[:l]
+
+		k, err := kf(item.Interface())
+		if err != nil {
+			e.setError(namespace, err)
+
+			continue
+		}
+
+		if e.e.escapeMapKeys {
+			k = escapeMapKey(k)
+		}
+
+		namespace = append(namespace, '[')
+		namespace = append(namespace, k...)
+		namespace = append(namespace, ']')
+
+		e.setFieldByType(item, namespace, -2, cachedField{})
+	}
+}
+
+func (e *encoder) setFormFields(namespace []byte, fields []KV) {
+	l := len(namespace)
+
+	for _, kv := range fields {
+		namespace = namespace[:l]
+
+		if kv.Key != "" {
+			if l > 0 {
+				namespace = append(namespace, namespaceSeparator)
+			}
+
+			namespace = append(namespace, kv.Key...)
+		}
+
+		e.setVal(namespace, reflect.ValueOf(kv.Value), kv.Value)
 	}
 }
 
 func (e *encoder) getMapKey(key reflect.Value, namespace []byte) (string, bool) {
+	if e.e.mapKeyFuncs != nil {
+		if kf, ok := e.e.mapKeyFuncs[key.Type()]; ok {
+			val, err := kf(key.Interface())
+			if err != nil {
+				e.setError(namespace, err)
+
+				return "", false
+			}
+
+			return val, true
+		}
+	}
+
 	v, kind := ExtractType(key)
 
 	if e.e.customTypeFuncs != nil {
@@ -282,9 +1159,16 @@ func (e *encoder) getMapKey(key reflect.Value, namespace []byte) (string, bool)
 		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
 
 	case reflect.Bool:
-		return strconv.FormatBool(v.Bool()), true
+		return e.formatBool(v.Bool()), true
 
 	default:
+		if kind == reflect.Struct || kind == reflect.Array {
+			e.setError(namespace, fmt.Errorf("unsupported map key type '%s' namespace '%s', "+
+				"register key serializer with RegisterMapKeyFunc", v.Type(), namespace))
+
+			return "", false
+		}
+
 		e.setError(namespace, fmt.Errorf("unsupported map key '%v' namespace '%s'", v.String(), namespace))
 
 		return "", false
@@ -1,10 +1,19 @@
 package form
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math/big"
+	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -1192,7 +1201,8 @@ func TestEncoderErrors(t *testing.T) {
 	Equal(t, k.Error(), "bad type conversion")
 
 	k = ee["Struct"]
-	Equal(t, k.Error(), "unsupported map key '<struct {} Value>' namespace 'Struct'")
+	Equal(t, k.Error(), "unsupported map key type 'struct {}' namespace 'Struct', "+
+		"register key serializer with RegisterMapKeyFunc")
 }
 
 func TestEncoderPanicsAndBadValues(t *testing.T) {
@@ -1504,3 +1514,3087 @@ func (e embeddedUnexported) MarshalText() (text []byte, err error) {
 func MakeEmbeddedUnexported() io.Writer {
 	return deeperEmbedded{}
 }
+
+func TestEncoder_Encode_omitDefault(t *testing.T) {
+	t.Parallel()
+
+	type Config struct {
+		Name   string
+		Labels map[string]string
+		Ports  []int
+	}
+
+	type Test struct {
+		Cfg    Config  `form:"cfg,omitdefault"`
+		CfgPtr *Config `form:"cfgPtr,omitdefault"`
+		Other  int     `form:"other"`
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(Test{CfgPtr: &Config{}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"other": {"0"}})
+
+	values, err = encoder.Encode(Test{
+		Cfg:    Config{Labels: map[string]string{"a": "b"}},
+		CfgPtr: &Config{Ports: []int{80}},
+	})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"cfg.Name":      {""},
+		"cfg.Labels[a]": {"b"},
+		"cfgPtr.Name":   {""},
+		"cfgPtr.Ports":  {"80"},
+		"other":         {"0"},
+	})
+}
+
+type formFielderPoint struct {
+	Lat, Lng float64
+}
+
+func (p formFielderPoint) FormFields() []KV {
+	return []KV{
+		{Value: strconv.FormatFloat(p.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lng, 'f', -1, 64)},
+		{Key: "lat", Value: strconv.FormatFloat(p.Lat, 'f', -1, 64)},
+		{Key: "lng", Value: strconv.FormatFloat(p.Lng, 'f', -1, 64)},
+	}
+}
+
+type formFielderAnonymous struct {
+	ID string
+}
+
+func (a formFielderAnonymous) FormFields() []struct{ Key, Value string } {
+	return []struct{ Key, Value string }{{Key: "id", Value: a.ID}}
+}
+
+func TestEncoder_Encode_formFielder(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Point  formFielderPoint   `form:"point"`
+		Ptr    *formFielderPoint  `form:"ptr"`
+		Points []formFielderPoint `form:"points"`
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(Test{
+		Point:  formFielderPoint{Lat: 1.5, Lng: 2},
+		Points: []formFielderPoint{{Lat: 3, Lng: 4}},
+	})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"point":         {"1.5,2"},
+		"point.lat":     {"1.5"},
+		"point.lng":     {"2"},
+		"points[0]":     {"3,4"},
+		"points[0].lat": {"3"},
+		"points[0].lng": {"4"},
+	})
+
+	values, err = encoder.Encode(formFielderPoint{Lat: 5, Lng: 6})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"":    {"5,6"},
+		"lat": {"5"},
+		"lng": {"6"},
+	})
+
+	var _ FormFielder = formFielderAnonymous{}
+
+	values, err = encoder.Encode(struct {
+		Ref formFielderAnonymous `form:"ref"`
+	}{Ref: formFielderAnonymous{ID: "42"}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"ref.id": {"42"}})
+}
+
+func TestEncoder_EncodeToWriter(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name   string             `form:"name"`
+		Token  string             `form:"token,noescape"`
+		Tags   []string           `form:"tags,noescape"`
+		Nested struct{ A string } `form:"nested"`
+	}
+
+	encoder := NewEncoder()
+	buf := bytes.NewBuffer(nil)
+
+	err := encoder.EncodeToWriter(buf, Test{
+		Name:  "a b&c",
+		Token: "x%2Fy=z",
+		Tags:  []string{"[1]", "2/3"},
+	})
+	Equal(t, err, nil)
+	Equal(t, buf.String(), "name=a+b%26c&token=x%2Fy=z&tags=[1]&tags=2/3&nested.A=")
+
+	err = encoder.EncodeToWriter(buf, nil)
+	NotEqual(t, err, nil)
+}
+
+func TestEncoder_EncodeToStringBuilder(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name  string   `form:"name"`
+		Token string   `form:"token,noescape"`
+		Tags  []string `form:"tags"`
+	}
+
+	encoder := NewEncoder()
+
+	var b strings.Builder
+
+	b.WriteString("https://example.com/?")
+
+	err := encoder.EncodeToStringBuilder(&b, Test{Name: "a b", Token: "x%2Fy", Tags: []string{"1", "2&3"}})
+	Equal(t, err, nil)
+	Equal(t, b.String(), "https://example.com/?name=a+b&token=x%2Fy&tags=1&tags=2%263")
+
+	err = encoder.EncodeToStringBuilder(&b, nil)
+	NotEqual(t, err, nil)
+}
+
+func TestEncoder_SetEmptyStructMarker(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		Name string `form:"name"`
+		Age  int    `form:"age,omitempty"`
+	}
+
+	type Test struct {
+		Inner  Inner    `form:"inner"`
+		Ptr    *Inner   `form:"ptr"`
+		Inners []Inner  `form:"inners"`
+		Empty  struct{} `form:"empty"`
+	}
+
+	encoder := NewEncoder()
+	encoder.SetEmptyStructMarker("__empty__")
+
+	values, err := encoder.Encode(Test{Ptr: &Inner{}, Inners: []Inner{{}, {Name: "a"}}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"inner":          {"__empty__"},
+		"ptr":            {"__empty__"},
+		"inners[0]":      {"__empty__"},
+		"inners[1].name": {"a"},
+		"empty":          {"__empty__"},
+	})
+
+	values, err = encoder.Encode(Test{Inner: Inner{Age: 1}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"inner.name": {""},
+		"inner.age":  {"1"},
+		"empty":      {"__empty__"},
+	})
+
+	values, err = encoder.Encode(Inner{})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {""}})
+}
+
+func TestEncoder_SetSliceLimitMode(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Items   []int    `form:"items,max=2"`
+		Ptrs    []*int   `form:"ptrs,max=1,omitempty"`
+		Array   [3]int   `form:"array,max=2"`
+		NoLimit []string `form:"noLimit"`
+	}
+
+	one, two := 1, 2
+	tst := Test{
+		Items:   []int{1, 2, 3},
+		Ptrs:    []*int{&one, &two},
+		Array:   [3]int{4, 5, 6},
+		NoLimit: []string{"a", "b", "c"},
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"items":   {"1", "2"},
+		"ptrs[0]": {"1"},
+		"array":   {"4", "5"},
+		"noLimit": {"a", "b", "c"},
+	})
+
+	encoder.SetSliceLimitMode(SliceLimitError)
+
+	values, err = encoder.Encode(tst)
+	NotEqual(t, err, nil)
+	Equal(t, len(err.(EncodeErrors)), 3)
+	Equal(t, err.(EncodeErrors)["items"].Error(), "length 3 exceeds maximum of 2 items")
+	Equal(t, values, url.Values{"noLimit": {"a", "b", "c"}})
+
+	type Test2 struct {
+		Items []int `form:"items,max=2"`
+	}
+
+	values, err = encoder.Encode(Test2{Items: []int{1, 2}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"items": {"1", "2"}})
+}
+
+func TestEncoder_SetSharedPointerMode(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `form:"city"`
+	}
+
+	type Test struct {
+		Home     *Address   `form:"home"`
+		Billing  *Address   `form:"billing"`
+		Previous []*Address `form:"previous"`
+	}
+
+	addr := &Address{City: "Berlin"}
+	tst := Test{Home: addr, Billing: addr, Previous: []*Address{{City: "Paris"}, addr}}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"home.city":        {"Berlin"},
+		"billing.city":     {"Berlin"},
+		"previous[0].city": {"Paris"},
+		"previous[1].city": {"Berlin"},
+	})
+
+	encoder.SetSharedPointerMode(SharedPointerReference)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"home.city":        {"Berlin"},
+		"billing":          {"@ref:home"},
+		"previous[0].city": {"Paris"},
+		"previous[1]":      {"@ref:home"},
+	})
+
+	// Pointers are tracked per call.
+	values, err = encoder.Encode(Test{Billing: addr})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"billing.city": {"Berlin"}})
+}
+
+func TestEncoder_SetZeroTimePlaceholder(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Time      time.Time   `form:"time"`
+		OmitEmpty time.Time   `form:"omitEmpty,omitempty"`
+		Ptr       *time.Time  `form:"ptr,omitempty"`
+		Times     []time.Time `form:"times"`
+	}
+
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(Test{})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"time": {"0001-01-01T00:00:00Z"}})
+
+	encoder.SetZeroTimePlaceholder("0001-01-01")
+
+	values, err = encoder.Encode(Test{Times: []time.Time{ts, {}}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"time":      {"0001-01-01"},
+		"omitEmpty": {"0001-01-01"},
+		"times[0]":  {"2024-01-02T15:04:05Z"},
+		"times[1]":  {"0001-01-01"},
+	})
+
+	encoder.SetZeroTimePlaceholder("")
+
+	values, err = encoder.Encode(Test{Time: ts, Ptr: &time.Time{}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"time":      {"2024-01-02T15:04:05Z"},
+		"omitEmpty": {""},
+		"ptr":       {""},
+	})
+}
+
+func TestEncoder_SetFallbackToJSONTag(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name    string `json:"name"`
+		Empty   string `json:"empty,omitempty"`
+		Skipped string `json:"-"`
+		Form    string `json:"json_form" form:"form"`
+		NoTag   int
+		Inner   struct {
+			Value string `json:"value"`
+		} `json:"inner"`
+	}
+
+	tst := Test{Name: "foo", Skipped: "bar", Form: "baz", NoTag: 1}
+	tst.Inner.Value = "qux"
+
+	encoder := NewEncoder()
+	encoder.SetFallbackToJSONTag(true)
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name":        {"foo"},
+		"form":        {"baz"},
+		"NoTag":       {"1"},
+		"inner.value": {"qux"},
+	})
+
+	encoder.SetMode(ModeExplicit)
+
+	values, err = encoder.Encode(struct {
+		Name  string `json:"name"`
+		NoTag int
+	}{Name: "foo"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"foo"}})
+}
+
+func TestEncoder_RegisterKeyFunc(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		Region string `form:"region"`
+		ID     int    `form:"id"`
+		Name   string `form:"name"`
+	}
+
+	type Test struct {
+		Items []Item   `form:"items"`
+		Ptrs  []*Item  `form:"ptrs"`
+		Other []string `form:"other"`
+	}
+
+	encoder := NewEncoder()
+	encoder.RegisterKeyFunc(func(x interface{}) (string, error) {
+		item := x.(Item)
+		if item.ID == 0 {
+			return "", errors.New("missing id")
+		}
+
+		return item.Region + ":" + strconv.Itoa(item.ID), nil
+	}, Item{})
+
+	tst := Test{
+		Items: []Item{{Region: "eu", ID: 1, Name: "a"}, {Region: "us", ID: 1, Name: "b"}},
+		Ptrs:  []*Item{nil, {Region: "eu", ID: 2, Name: "c"}},
+		Other: []string{"x"},
+	}
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"items[eu:1].region": {"eu"},
+		"items[eu:1].id":     {"1"},
+		"items[eu:1].name":   {"a"},
+		"items[us:1].region": {"us"},
+		"items[us:1].id":     {"1"},
+		"items[us:1].name":   {"b"},
+		"ptrs[eu:2].region":  {"eu"},
+		"ptrs[eu:2].id":      {"2"},
+		"ptrs[eu:2].name":    {"c"},
+		"other":              {"x"},
+	})
+
+	var decoded struct {
+		Items map[string]Item `form:"items"`
+	}
+
+	err = NewDecoder().Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded.Items, map[string]Item{"eu:1": tst.Items[0], "us:1": tst.Items[1]})
+
+	_, err = encoder.Encode(Test{Items: []Item{{Name: "no id"}}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:items ERROR:missing id")
+}
+
+func TestEncoder_Encode_concurrent(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		ID    int               `form:"id"`
+		Map   map[string]string `form:"map"`
+		Items []*Test           `form:"items,omitempty"`
+	}
+
+	encoder := NewEncoder()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			id := strconv.Itoa(i)
+
+			values, err := encoder.Encode(Test{
+				ID:    i,
+				Map:   map[string]string{id: id},
+				Items: []*Test{{ID: i}},
+			})
+			Equal(t, err, nil)
+			Equal(t, values, url.Values{
+				"id":              {id},
+				"map[" + id + "]": {id},
+				"items[0].id":     {id},
+			})
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestEncoder_Encode_arrayPointers(t *testing.T) {
+	t.Parallel()
+
+	one, two, three := 1, 2, 3
+	arr := [3]int{1, 2, 3}
+
+	for _, tc := range []struct {
+		name     string
+		value    interface{}
+		expected url.Values
+	}{
+		{
+			name:     "array",
+			value:    struct{ F [3]int }{F: arr},
+			expected: url.Values{"F": {"1", "2", "3"}},
+		},
+		{
+			name:     "pointer to array",
+			value:    struct{ F *[3]int }{F: &arr},
+			expected: url.Values{"F": {"1", "2", "3"}},
+		},
+		{
+			name:     "nil pointer to array",
+			value:    struct{ F *[3]int }{},
+			expected: url.Values{},
+		},
+		{
+			name:     "array of pointers",
+			value:    struct{ F [3]*int }{F: [3]*int{&one, &two, &three}},
+			expected: url.Values{"F[0]": {"1"}, "F[1]": {"2"}, "F[2]": {"3"}},
+		},
+		{
+			name:     "array of pointers with nil",
+			value:    struct{ F [3]*int }{F: [3]*int{&one, nil, &three}},
+			expected: url.Values{"F[0]": {"1"}, "F[2]": {"3"}},
+		},
+		{
+			name:     "pointer to array of pointers",
+			value:    struct{ F *[3]*int }{F: &[3]*int{nil, &two, nil}},
+			expected: url.Values{"F[1]": {"2"}},
+		},
+		{
+			name:     "slice of pointers to arrays",
+			value:    struct{ F []*[2]int }{F: []*[2]int{{1, 2}, nil, {3, 4}}},
+			expected: url.Values{"F[0][0]": {"1"}, "F[0][1]": {"2"}, "F[2][0]": {"3"}, "F[2][1]": {"4"}},
+		},
+		{
+			name:     "pointer to slice of pointers",
+			value:    struct{ F *[]*int }{F: &[]*int{nil, &one}},
+			expected: url.Values{"F[1]": {"1"}},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			values, err := NewEncoder().Encode(tc.value)
+			Equal(t, err, nil)
+			Equal(t, values, tc.expected)
+
+			decoded := reflect.New(reflect.TypeOf(tc.value))
+			err = NewDecoder().Decode(decoded.Interface(), values)
+			Equal(t, err, nil)
+			Equal(t, decoded.Elem().Interface(), tc.value)
+		})
+	}
+}
+
+func TestEncoder_Encode_countKey(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		Name string `form:"name"`
+	}
+
+	type Inner struct {
+		Tags []string `form:"tags,count=tags_count"`
+	}
+
+	type Test struct {
+		Items   []Item   `form:"items,count=items_count"`
+		Limited []int    `form:"limited,max=2,count=limited_count"`
+		Ptr     *[]int   `form:"ptr,count=ptr_count"`
+		Empty   []string `form:"empty,omitempty,count=empty_count"`
+		Inner   Inner    `form:"inner"`
+	}
+
+	encoder := NewEncoder()
+
+	values, columns, err := encoder.EncodeWithColumns(Test{
+		Items:   []Item{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		Limited: []int{1, 2, 3},
+		Inner:   Inner{Tags: []string{"x"}},
+	})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"items[0].name":    {"a"},
+		"items[1].name":    {"b"},
+		"items[2].name":    {"c"},
+		"items_count":      {"3"},
+		"limited":          {"1", "2"},
+		"limited_count":    {"2"},
+		"empty_count":      {"0"},
+		"inner.tags":       {"x"},
+		"inner.tags_count": {"1"},
+	})
+	Equal(t, columns, []string{
+		"items[0].name", "items[1].name", "items[2].name", "items_count",
+		"limited", "limited_count", "empty_count", "inner.tags", "inner.tags_count",
+	})
+}
+
+func TestEncoder_Schema(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `form:"city,required"`
+		Zip  string `form:"zip,omitempty"`
+	}
+
+	type Embedded struct {
+		Note string `form:"note"`
+	}
+
+	type Test struct {
+		Embedded
+		Name     string            `form:"name,required"`
+		Address  Address           `form:"address"`
+		Previous *Address          `form:"previous,omitempty"`
+		Tags     []string          `form:"tags,max=10,count=tags_count"`
+		Items    []Address         `form:"items"`
+		Created  time.Time         `form:"created"`
+		Value    textMarshaler     `form:"value"`
+		Labels   map[string]string `form:"labels"`
+		Skipped  string            `form:"-"`
+	}
+
+	encoder := NewEncoder()
+
+	Equal(t, encoder.Schema(&Test{}), []FieldSchema{
+		{Namespace: "note", Type: reflect.TypeOf("")},
+		{Namespace: "name", Type: reflect.TypeOf(""), Required: true, Options: []string{"required"}},
+		{Namespace: "address.city", Type: reflect.TypeOf(""), Required: true, Options: []string{"required"}},
+		{Namespace: "address.zip", Type: reflect.TypeOf(""), Options: []string{"omitempty"}},
+		{Namespace: "previous.city", Type: reflect.TypeOf(""), Required: true, Options: []string{"required"}},
+		{Namespace: "previous.zip", Type: reflect.TypeOf(""), Options: []string{"omitempty"}},
+		{Namespace: "tags", Type: reflect.TypeOf([]string{}), Options: []string{"max=10", "count=tags_count"}},
+		{Namespace: "items", Type: reflect.TypeOf([]Address{})},
+		{Namespace: "created", Type: reflect.TypeOf(time.Time{})},
+		{Namespace: "value", Type: reflect.TypeOf(textMarshaler(""))},
+		{Namespace: "labels", Type: reflect.TypeOf(map[string]string{})},
+	})
+
+	type Node struct {
+		Name string `form:"name"`
+		Next *Node  `form:"next"`
+	}
+
+	Equal(t, encoder.Schema(Node{}), []FieldSchema{{Namespace: "name", Type: reflect.TypeOf("")}})
+	Equal(t, len(encoder.Schema(1)), 0)
+	Equal(t, len(encoder.Schema(nil)), 0)
+}
+
+func TestEncoderInterfaceSlice(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		Name string `form:"name"`
+	}
+
+	type Test struct {
+		Mixed []interface{} `form:"mixed"`
+	}
+
+	one := 1
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(Test{Mixed: []interface{}{"str", 2, Inner{Name: "inner"}, nil, &one}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"mixed[0]":      {"str"},
+		"mixed[1]":      {"2"},
+		"mixed[2].name": {"inner"},
+		"mixed[4]":      {"1"},
+	})
+
+	var decoded Test
+
+	err = NewDecoder().Decode(&decoded, url.Values{"mixed[0]": {"str"}, "mixed[1]": {"2"}})
+	Equal(t, err, nil)
+	Equal(t, decoded.Mixed, []interface{}{"str", "2"})
+}
+
+func TestEncoder_SetFlattenSingleMap(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Single map[string]int    `form:"single"`
+		Multi  map[string]int    `form:"multi"`
+		Nested []map[string]bool `form:"nested"`
+	}
+
+	tst := Test{
+		Single: map[string]int{"a": 1},
+		Multi:  map[string]int{"a": 1, "b": 2},
+		Nested: []map[string]bool{{"ok": true}},
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"single[a]":     {"1"},
+		"multi[a]":      {"1"},
+		"multi[b]":      {"2"},
+		"nested[0][ok]": {"true"},
+	})
+
+	encoder.SetFlattenSingleMap(true)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"single":    {"1"},
+		"multi[a]":  {"1"},
+		"multi[b]":  {"2"},
+		"nested[0]": {"true"},
+	})
+}
+
+func TestEncoderSplitTime(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Appt     time.Time  `form:"appt,split=date:time"`
+		Optional *time.Time `form:"optional,split=day:hour"`
+		Skipped  time.Time  `form:"skipped,split=date:time,omitempty"`
+	}
+
+	tst := Test{Appt: time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"appt_date": {"2024-01-02"},
+		"appt_time": {"15:04"},
+	})
+
+	opt := time.Date(2024, 2, 3, 8, 30, 0, 0, time.UTC)
+	tst.Optional = &opt
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"appt_date":     {"2024-01-02"},
+		"appt_time":     {"15:04"},
+		"optional_day":  {"2024-02-03"},
+		"optional_hour": {"08:30"},
+	})
+
+	var decoded Test
+
+	decoder := NewDecoder()
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	decoded = Test{}
+	err = decoder.Decode(&decoded, url.Values{"appt_date": {"2024-01-02"}})
+	Equal(t, err, nil)
+	Equal(t, decoded.Appt, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	err = decoder.Decode(&decoded, url.Values{"appt_date": {"2024-01-02"}, "appt_time": {"25:00"}})
+	NotEqual(t, err, nil)
+}
+
+func TestEncoder_SetBoolFunc(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		Active bool `form:"active"`
+	}
+
+	type Test struct {
+		Flag   bool         `form:"flag"`
+		Flags  []bool       `form:"flags"`
+		ByFlag map[bool]int `form:"by_flag"`
+		Inner  Inner        `form:"inner"`
+		Ptr    *bool        `form:"ptr"`
+	}
+
+	yes := true
+	tst := Test{
+		Flag:   true,
+		Flags:  []bool{true, false},
+		ByFlag: map[bool]int{false: 1},
+		Inner:  Inner{Active: true},
+		Ptr:    &yes,
+	}
+
+	encoder := NewEncoder()
+	encoder.SetBoolFunc(func(b bool) string {
+		if b {
+			return "Y"
+		}
+
+		return "N"
+	})
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"flag":         {"Y"},
+		"flags":        {"Y", "N"},
+		"by_flag[N]":   {"1"},
+		"inner.active": {"Y"},
+		"ptr":          {"Y"},
+	})
+
+	decoder := NewDecoder()
+	decoder.SetBoolParseFunc(func(s string) (bool, error) {
+		switch s {
+		case "Y":
+			return true, nil
+		case "N":
+			return false, nil
+		}
+
+		return false, strconv.ErrSyntax
+	})
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	err = decoder.Decode(&decoded, url.Values{"flag": {"true"}})
+	NotEqual(t, err, nil)
+}
+
+func TestEncoder_RegisteredTypes(t *testing.T) {
+	t.Parallel()
+
+	encoder := NewEncoder()
+	Equal(t, len(encoder.RegisteredTypes()), 0)
+
+	fn := func(x interface{}) (string, error) {
+		return "", nil
+	}
+
+	encoder.RegisterFunc(fn, time.Time{}, int64(0))
+	encoder.RegisterFunc(fn, &url.URL{})
+
+	Equal(t, encoder.RegisteredTypes(), []reflect.Type{
+		reflect.TypeOf(&url.URL{}),
+		reflect.TypeOf(int64(0)),
+		reflect.TypeOf(time.Time{}),
+	})
+}
+
+func TestEncoder_EncodeSparse(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		Name  string `form:"name"`
+		Count int    `form:"count"`
+	}
+
+	type Test struct {
+		Name   string   `form:"name"`
+		Count  int      `form:"count"`
+		Flag   bool     `form:"flag"`
+		Tags   []string `form:"tags"`
+		Ints   []int    `form:"ints"`
+		Inner  Inner    `form:"inner"`
+		Ptr    *Inner   `form:"ptr"`
+		Filled Inner    `form:"filled"`
+	}
+
+	tst := Test{Name: "a", Ints: []int{0, 1}, Filled: Inner{Count: 1}}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name":         {"a"},
+		"count":        {"0"},
+		"flag":         {"false"},
+		"ints":         {"0", "1"},
+		"inner.name":   {""},
+		"inner.count":  {"0"},
+		"filled.name":  {""},
+		"filled.count": {"1"},
+	})
+
+	values, err = encoder.EncodeSparse(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name":         {"a"},
+		"ints":         {"0", "1"},
+		"filled.count": {"1"},
+	})
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, len(values), 8)
+}
+
+func TestEncoder_SetIndexStyle(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Tags  []string         `form:"tags"`
+		Multi map[string][]int `form:"multi"`
+	}
+
+	tst := Test{
+		Tags:  []string{"a", "b"},
+		Multi: map[string][]int{"x": {1, 2}, "y": {3}},
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"tags":        {"a", "b"},
+		"multi[x][0]": {"1"},
+		"multi[x][1]": {"2"},
+		"multi[y][0]": {"3"},
+	})
+
+	encoder.SetIndexStyle(IndexStyleIndexed)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"tags[0]":     {"a"},
+		"tags[1]":     {"b"},
+		"multi[x][0]": {"1"},
+		"multi[x][1]": {"2"},
+		"multi[y][0]": {"3"},
+	})
+
+	decoder := NewDecoder()
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	encoder.SetIndexStyle(IndexStyleRepeated)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"tags":     {"a", "b"},
+		"multi[x]": {"1", "2"},
+		"multi[y]": {"3"},
+	})
+
+	decoded = Test{}
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestEncoder_SetSkipFunc(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		Note string `form:"note"`
+	}
+
+	type Test struct {
+		Name  string   `form:"name"`
+		Bio   string   `form:"bio,omitempty"`
+		Tags  []string `form:"tags"`
+		Inner Inner    `form:"inner"`
+		Count int      `form:"count"`
+	}
+
+	encoder := NewEncoder()
+	encoder.SetSkipFunc(func(namespace string, v reflect.Value) bool {
+		return v.Kind() == reflect.String && v.Len() > 5
+	})
+
+	values, err := encoder.Encode(Test{
+		Name:  "short",
+		Tags:  []string{"a", "very long tag"},
+		Inner: Inner{Note: "long note"},
+		Count: 123456,
+	})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name":  {"short"},
+		"tags":  {"a"},
+		"count": {"123456"},
+	})
+}
+
+func TestEncoder_SetJSONBelowDepth(t *testing.T) {
+	t.Parallel()
+
+	type Leaf struct {
+		Value int `form:"value" json:"value"`
+	}
+
+	type Middle struct {
+		Name string `form:"name" json:"name"`
+		Leaf Leaf   `form:"leaf" json:"leaf"`
+	}
+
+	type Test struct {
+		ID     int     `form:"id"`
+		Middle Middle  `form:"middle"`
+		Ptr    *Middle `form:"ptr"`
+	}
+
+	tst := Test{ID: 1, Middle: Middle{Name: "m", Leaf: Leaf{Value: 2}}}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"id":                {"1"},
+		"middle.name":       {"m"},
+		"middle.leaf.value": {"2"},
+	})
+
+	encoder.SetJSONBelowDepth(1)
+
+	tst.Ptr = &Middle{Name: "p"}
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"id":          {"1"},
+		"middle.name": {"m"},
+		"middle.leaf": {`{"value":2}`},
+		"ptr.name":    {"p"},
+		"ptr.leaf":    {`{"value":0}`},
+	})
+
+	decoder := NewDecoder()
+	decoder.SetJSONBelowDepth(1)
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	err = decoder.Decode(&decoded, url.Values{"middle.leaf": {"{"}})
+	NotEqual(t, err, nil)
+}
+
+type testTimestamp struct {
+	time.Time
+}
+
+type testDate struct {
+	time.Time
+}
+
+func (d testDate) MarshalText() ([]byte, error) {
+	return []byte(d.Format("2006-01-02")), nil
+}
+
+func (d *testDate) UnmarshalText(text []byte) error {
+	t, err := time.Parse("2006-01-02", string(text))
+	d.Time = t
+
+	return err
+}
+
+func TestEncoderTimeWrapper(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		At    testTimestamp            `form:"at"`
+		Ptr   *testTimestamp           `form:"ptr"`
+		Slice []testTimestamp          `form:"slice"`
+		Map   map[string]testTimestamp `form:"map"`
+	}
+
+	ts := testTimestamp{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	tst := Test{At: ts, Ptr: &ts, Slice: []testTimestamp{ts}, Map: map[string]testTimestamp{"a": ts}}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"at":       {"2024-01-02T03:04:05Z"},
+		"ptr":      {"2024-01-02T03:04:05Z"},
+		"slice[0]": {"2024-01-02T03:04:05Z"},
+		"map[a]":   {"2024-01-02T03:04:05Z"},
+	})
+
+	var decoded Test
+
+	err = NewDecoder().Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	values, err = encoder.Encode(ts)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"": {"2024-01-02T03:04:05Z"}})
+
+	var decodedTS testTimestamp
+
+	err = NewDecoder().Decode(&decodedTS, values)
+	Equal(t, err, nil)
+	Equal(t, decodedTS, ts)
+
+	// wrappers with their own text methods use them
+	type DateTest struct {
+		Date testDate `form:"date"`
+	}
+
+	var dt DateTest
+
+	err = NewDecoder().Decode(&dt, url.Values{"date": {"2024-01-02"}})
+	Equal(t, err, nil)
+	Equal(t, dt.Date.Time, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	values, err = encoder.Encode(dt)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"date": {"2024-01-02"}})
+}
+
+func TestEncoderKeyCase(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		UserID  int    `form:"UserID,keycase=lower"`
+		Region  string `form:"Region,keycase=upper"`
+		Name    string `form:"Name"`
+		Default string `form:",keycase=lower"`
+	}
+
+	tst := Test{UserID: 1, Region: "eu", Name: "n", Default: "d"}
+
+	values, err := NewEncoder().Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"userid":  {"1"},
+		"REGION":  {"eu"},
+		"Name":    {"n"},
+		"default": {"d"},
+	})
+
+	var decoded Test
+
+	err = NewDecoder().Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	// forced case applies after other name transforms
+	type Transformed struct {
+		Tags  []string `form:"Tag,keycase=upper"`
+		Label string   `form:"label,keycase=upper"`
+		Plain string   `form:"plain"`
+	}
+
+	contextual := func(parent reflect.Type, goField string, tag string) string {
+		return "x_" + tag
+	}
+
+	encoder := NewEncoder()
+	encoder.SetPluralizeSliceKeys(true)
+	encoder.SetContextualNameFunc(contextual)
+
+	decoder := NewDecoder()
+	decoder.SetPluralizeSliceKeys(true)
+	decoder.SetContextualNameFunc(contextual)
+
+	tr := Transformed{Tags: []string{"a"}, Label: "l", Plain: "p"}
+
+	values, err = encoder.Encode(tr)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"X_TAGS": {"a"}, "X_LABEL": {"l"}, "x_plain": {"p"}})
+
+	var decodedTr Transformed
+
+	err = decoder.Decode(&decodedTr, values)
+	Equal(t, err, nil)
+	Equal(t, decodedTr, tr)
+
+	values, err = NewEncoder().Encode(testKeyCaseNamer{Label: "l"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"NAMED_LABEL": {"l"}})
+
+	// tag name func does not drop forced case
+	tagFn := func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("form"), ",", 2)[0]
+	}
+
+	encoder = NewEncoder()
+	encoder.RegisterTagNameFunc(tagFn)
+
+	decoder = NewDecoder()
+	decoder.RegisterTagNameFunc(tagFn)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"userid": {"1"}, "REGION": {"eu"}, "Name": {"n"}, "default": {"d"}})
+
+	decoded = Test{}
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+type testKeyCaseNamer struct {
+	Label string `form:"label,keycase=upper"`
+}
+
+func (testKeyCaseNamer) FormFieldName(goField string) string {
+	return "named_" + strings.ToLower(goField)
+}
+
+type testPoint struct {
+	Lat float64
+	Lng float64
+}
+
+func TestEncoder_RegisterTemplate(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Point  testPoint   `form:"point"`
+		Points []testPoint `form:"points"`
+	}
+
+	encoder := NewEncoder()
+
+	err := encoder.RegisterTemplate(testPoint{}, "{{.Lat}},{{.Lng}}")
+	Equal(t, err, nil)
+
+	values, err := encoder.Encode(Test{
+		Point:  testPoint{Lat: 12.3, Lng: 45.6},
+		Points: []testPoint{{Lat: 1, Lng: 2}},
+	})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"point":     {"12.3,45.6"},
+		"points[0]": {"1,2"},
+	})
+
+	err = encoder.RegisterTemplate(testPoint{}, "{{.Lat")
+	NotEqual(t, err, nil)
+
+	err = encoder.RegisterTemplate(testPoint{}, "{{.Alt}}")
+	Equal(t, err, nil)
+
+	_, err = encoder.Encode(Test{})
+	NotEqual(t, err, nil)
+}
+
+func TestEncoder_SetOmitEmptyPolicy(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		Count int `form:"count"`
+	}
+
+	type Test struct {
+		Int     int     `form:"int"`
+		Uint    uint8   `form:"uint"`
+		Float   float64 `form:"float"`
+		String  string  `form:"string,omitempty"`
+		Bool    bool    `form:"bool"`
+		Filled  int     `form:"filled"`
+		Inner   Inner   `form:"inner"`
+		Pointer *int    `form:"pointer"`
+	}
+
+	encoder := NewEncoder()
+	encoder.SetOmitEmptyPolicy(map[reflect.Kind]bool{
+		reflect.Int:     true,
+		reflect.Uint8:   true,
+		reflect.Float64: true,
+		reflect.String:  false,
+	})
+
+	values, err := encoder.Encode(Test{Filled: 1})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"string": {""},
+		"bool":   {"false"},
+		"filled": {"1"},
+	})
+}
+
+func TestEncoder_SetChecksumKey(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name string   `form:"name"`
+		Tags []string `form:"tags"`
+		Sum  string   `form:"sum"`
+	}
+
+	sum := func(b []byte) string {
+		return fmt.Sprintf("%x", sha256.Sum256(b))
+	}
+
+	encoder := NewEncoder()
+	encoder.SetChecksumKey("sum", sum)
+
+	values, err := encoder.Encode(Test{Name: "a b", Tags: []string{"x", "y"}, Sum: "ignored"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name": {"a b"},
+		"tags": {"x", "y"},
+		"sum":  {sum([]byte("name=a+b&tags=x&tags=y"))},
+	})
+
+	values, columns, err := encoder.EncodeWithColumns(Test{Name: "a"})
+	Equal(t, err, nil)
+	Equal(t, columns, []string{"name", "sum"})
+	Equal(t, values["sum"], []string{sum([]byte("name=a"))})
+}
+
+func TestEncoder_SetSequencePrefix(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		B string `form:"b"`
+	}
+
+	type Test struct {
+		Z     string `form:"z"`
+		A     []int  `form:"a"`
+		Inner Inner  `form:"inner"`
+		M     string `form:"m"`
+	}
+
+	tst := Test{Z: "z", A: []int{1, 2}, Inner: Inner{B: "b"}, M: "m"}
+
+	encoder := NewEncoder()
+	encoder.SetSequencePrefix(true, 3)
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"001:z":       {"z"},
+		"002:a":       {"1", "2"},
+		"003:inner.b": {"b"},
+		"004:m":       {"m"},
+	})
+
+	_, columns, err := encoder.EncodeWithColumns(tst)
+	Equal(t, err, nil)
+	Equal(t, columns, []string{"001:z", "002:a", "003:inner.b", "004:m"})
+
+	var buf bytes.Buffer
+
+	err = encoder.EncodeToWriter(&buf, tst)
+	Equal(t, err, nil)
+	Equal(t, buf.String(), "001%3Az=z&002%3Aa=1&002%3Aa=2&003%3Ainner.b=b&004%3Am=m")
+
+	decoder := NewDecoder()
+	decoder.SetSequencePrefix(true)
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestEncoderBoolAndFloatMapKeys(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		ByBool  map[bool]int       `form:"by_bool"`
+		ByFloat map[float64]string `form:"by_float"`
+		ByF32   map[float32]bool   `form:"by_f32"`
+	}
+
+	tst := Test{
+		ByBool:  map[bool]int{true: 1, false: 2},
+		ByFloat: map[float64]string{1.5: "a", -2: "b", 1e21: "c"},
+		ByF32:   map[float32]bool{0.1: true},
+	}
+
+	values, err := NewEncoder().Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"by_bool[true]":                    {"1"},
+		"by_bool[false]":                   {"2"},
+		"by_float[1.5]":                    {"a"},
+		"by_float[-2]":                     {"b"},
+		"by_float[1000000000000000000000]": {"c"},
+		"by_f32[0.1]":                      {"true"},
+	})
+
+	var decoded Test
+
+	err = NewDecoder().Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestEncoder_EncodeMasked(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `form:"city"`
+		Zip  string `form:"zip"`
+	}
+
+	type Test struct {
+		Name    string    `form:"name"`
+		Age     int       `form:"age"`
+		Address Address   `form:"address"`
+		Others  []Address `form:"others"`
+		Billing *Address  `form:"billing"`
+	}
+
+	type AddressMask struct {
+		City bool
+	}
+
+	type Mask struct {
+		Name    bool
+		Age     bool
+		Address AddressMask
+		Others  *AddressMask
+		Billing bool
+	}
+
+	tst := Test{
+		Name:    "n",
+		Age:     3,
+		Address: Address{City: "c", Zip: "z"},
+		Others:  []Address{{City: "o", Zip: "oz"}},
+		Billing: &Address{City: "b", Zip: "bz"},
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.EncodeMasked(tst, Mask{Name: true, Address: AddressMask{City: true}, Billing: true})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name":         {"n"},
+		"address.city": {"c"},
+		"billing.city": {"b"},
+		"billing.zip":  {"bz"},
+	})
+
+	values, err = encoder.EncodeMasked(&tst, &Mask{Age: true, Others: &AddressMask{City: true}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"age":            {"3"},
+		"others[0].city": {"o"},
+	})
+
+	_, err = encoder.EncodeMasked(tst, true)
+	NotEqual(t, err, nil)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, len(values), 8)
+}
+
+func TestEncoder_SetNilStructMarker(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		Name string `form:"name,omitempty"`
+	}
+
+	type Test struct {
+		Nil    *Inner   `form:"nil"`
+		Empty  *Inner   `form:"empty"`
+		Filled *Inner   `form:"filled"`
+		Items  []*Inner `form:"items"`
+	}
+
+	tst := Test{Empty: &Inner{}, Filled: &Inner{Name: "a"}, Items: []*Inner{nil, {Name: "b"}}}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"filled.name":   {"a"},
+		"items[1].name": {"b"},
+	})
+
+	encoder.SetNilStructMarker("__nil__")
+	encoder.SetEmptyStructMarker("__empty__")
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"nil":           {"__nil__"},
+		"empty":         {"__empty__"},
+		"filled.name":   {"a"},
+		"items[0]":      {"__nil__"},
+		"items[1].name": {"b"},
+	})
+
+	decoder := NewDecoder()
+	decoder.SetEmptyStructMarker("__empty__")
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestEncoder_SetOmitDefaults(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Limit   int     `form:"limit,default=10"`
+		Sort    string  `form:"sort,default=asc"`
+		Ratio   float64 `form:"ratio,default=0.5"`
+		Active  bool    `form:"active,default=true"`
+		Page    *uint   `form:"page,default=1"`
+		Name    string  `form:"name"`
+		Changed int     `form:"changed,default=1"`
+	}
+
+	page := uint(1)
+	tst := Test{Limit: 10, Sort: "asc", Ratio: 0.5, Active: true, Page: &page, Name: "a", Changed: 2}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, len(values), 7)
+
+	encoder.SetOmitDefaults(true)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name":    {"a"},
+		"changed": {"2"},
+	})
+
+	values, err = encoder.Encode(Test{Limit: 20, Sort: "desc", Ratio: 1})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"limit":   {"20"},
+		"sort":    {"desc"},
+		"ratio":   {"1"},
+		"active":  {"false"},
+		"name":    {""},
+		"changed": {"0"},
+	})
+}
+
+type testLocalized struct {
+	French bool   `form:"-"`
+	Name   string `form:"name"`
+	City   string `form:"city"`
+	Zip    string `form:"zip"`
+}
+
+func (l testLocalized) FormFieldName(goFieldName string) string {
+	if !l.French {
+		return ""
+	}
+
+	switch goFieldName {
+	case "Name":
+		return "nom"
+	case "City":
+		return "ville"
+	}
+
+	return ""
+}
+
+func TestEncoderFieldNamer(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Person testLocalized   `form:"person"`
+		People []testLocalized `form:"people"`
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(testLocalized{Name: "a", City: "b", Zip: "c"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name": {"a"},
+		"city": {"b"},
+		"zip":  {"c"},
+	})
+
+	values, err = encoder.Encode(Test{
+		Person: testLocalized{French: true, Name: "a", City: "b", Zip: "c"},
+		People: []testLocalized{{Name: "d"}, {French: true, Name: "e"}},
+	})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"person.nom":      {"a"},
+		"person.ville":    {"b"},
+		"person.zip":      {"c"},
+		"people[0].name":  {"d"},
+		"people[0].city":  {""},
+		"people[0].zip":   {""},
+		"people[1].nom":   {"e"},
+		"people[1].ville": {""},
+		"people[1].zip":   {""},
+	})
+}
+
+func TestEncoder_SetTimeLayout(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		At    time.Time    `form:"at"`
+		Times []time.Time  `form:"times"`
+		Ptrs  []*time.Time `form:"ptrs"`
+	}
+
+	t1 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	t2 := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	tst := Test{At: t1, Times: []time.Time{t1, t2}, Ptrs: []*time.Time{&t2, nil, &t1}}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"at":       {"2024-01-02T00:00:00Z"},
+		"times[0]": {"2024-01-02T00:00:00Z"},
+		"times[1]": {"2024-03-04T00:00:00Z"},
+		"ptrs[0]":  {"2024-03-04T00:00:00Z"},
+		"ptrs[2]":  {"2024-01-02T00:00:00Z"},
+	})
+
+	encoder.SetTimeLayout("02.01.2006")
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"at":       {"02.01.2024"},
+		"times[0]": {"02.01.2024"},
+		"times[1]": {"04.03.2024"},
+		"ptrs[0]":  {"04.03.2024"},
+		"ptrs[2]":  {"02.01.2024"},
+	})
+
+	decoder := NewDecoder()
+	decoder.SetTimeLayouts("02.01.2006")
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestEncoder_SetRootKey(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		City string `form:"city"`
+	}
+
+	type Test struct {
+		Name  string         `form:"name"`
+		Tags  []string       `form:"tags"`
+		Inner Inner          `form:"inner"`
+		Attrs map[string]int `form:"attrs"`
+	}
+
+	tst := Test{Name: "a", Tags: []string{"b", "c"}, Inner: Inner{City: "d"}, Attrs: map[string]int{"e": 1}}
+
+	encoder := NewEncoder()
+	encoder.SetRootKey("data")
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"data.name":       {"a"},
+		"data.tags":       {"b", "c"},
+		"data.inner.city": {"d"},
+		"data.attrs[e]":   {"1"},
+	})
+
+	decoder := NewDecoder()
+	decoder.SetRootKey("data")
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	values, err = encoder.Encode(5)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"data": {"5"}})
+
+	var i int
+
+	err = decoder.Decode(&i, values)
+	Equal(t, err, nil)
+	Equal(t, i, 5)
+}
+
+func TestEncoder_SetRequiredMode(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name  string `form:"name,required"`
+		Email string `form:"email,required"`
+		Age   int    `form:"age"`
+	}
+
+	tst := Test{Email: "a@b.c"}
+
+	encoder := NewEncoder()
+
+	_, err := encoder.Encode(tst)
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:name ERROR:required field is empty")
+
+	values, err := encoder.Encode(Test{Name: "a", Email: "b"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"a"}, "email": {"b"}, "age": {"0"}})
+
+	encoder.SetRequiredMode(RequiredPlaceholder)
+	encoder.SetRequiredPlaceholder("__missing__")
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"__missing__"}, "email": {"a@b.c"}, "age": {"0"}})
+
+	var warnings []string
+
+	encoder.SetRequiredMode(RequiredWarn)
+	encoder.SetWarnFunc(func(namespace, msg string) {
+		warnings = append(warnings, namespace+": "+msg)
+	})
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {""}, "email": {"a@b.c"}, "age": {"0"}})
+	Equal(t, warnings, []string{"name: required field is empty"})
+}
+
+type (
+	testTags  []string
+	testAttrs map[string]string
+	testIDs   [2]int
+)
+
+func TestEncoderNamedCollections(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Tags    testTags    `form:"tags"`
+		Attrs   testAttrs   `form:"attrs"`
+		IDs     testIDs     `form:"ids"`
+		TagsPtr *testTags   `form:"tags_ptr"`
+		Nested  []testAttrs `form:"nested"`
+	}
+
+	tags := testTags{"c"}
+	tst := Test{
+		Tags:    testTags{"a", "b"},
+		Attrs:   testAttrs{"k": "v"},
+		IDs:     testIDs{1, 2},
+		TagsPtr: &tags,
+		Nested:  []testAttrs{{"x": "y"}},
+	}
+
+	values, err := NewEncoder().Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"tags":         {"a", "b"},
+		"attrs[k]":     {"v"},
+		"ids":          {"1", "2"},
+		"tags_ptr":     {"c"},
+		"nested[0][x]": {"y"},
+	})
+
+	var decoded Test
+
+	err = NewDecoder().Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+type testCivilDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func TestEncoder_RegisterStringerLayout(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Born  testCivilDate   `form:"born"`
+		Dates []testCivilDate `form:"dates"`
+		Next  *testCivilDate  `form:"next"`
+	}
+
+	encoder := NewEncoder()
+	encoder.RegisterStringerLayout(testCivilDate{}, func(x interface{}) string {
+		d := x.(testCivilDate)
+
+		return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	})
+
+	values, err := encoder.Encode(Test{
+		Born:  testCivilDate{Year: 1999, Month: time.December, Day: 31},
+		Dates: []testCivilDate{{Year: 2024, Month: time.February, Day: 29}},
+		Next:  &testCivilDate{Year: 2025, Month: time.January, Day: 1},
+	})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"born":     {"1999-12-31"},
+		"dates[0]": {"2024-02-29"},
+		"next":     {"2025-01-01"},
+	})
+}
+
+func TestEncoder_SetSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name    string `form:"name"`
+		Version string `form:"_v"`
+	}
+
+	encoder := NewEncoder()
+	encoder.SetSchemaVersion("_v", "2")
+
+	values, err := encoder.Encode(Test{Name: "a", Version: "1"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"a"}, "_v": {"2"}})
+
+	_, columns, err := encoder.EncodeWithColumns(struct {
+		Name string `form:"name"`
+	}{Name: "a"})
+	Equal(t, err, nil)
+	Equal(t, columns, []string{"name", "_v"})
+
+	decoder := NewDecoder()
+	Equal(t, decoder.SchemaVersion(values), "")
+
+	decoder.SetSchemaVersionKey("_v")
+	Equal(t, decoder.SchemaVersion(values), "2")
+	Equal(t, decoder.SchemaVersion(url.Values{}), "")
+}
+
+func TestEncoder_SetNilElementPlaceholder(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		Name string `form:"name"`
+	}
+
+	type Test struct {
+		Ints   []*int     `form:"ints"`
+		Items  []*Item    `form:"items"`
+		Nested [][]*int   `form:"nested"`
+		Arr    [3]*string `form:"arr"`
+	}
+
+	one, two := 1, 2
+	s := "s"
+	tst := Test{
+		Ints:   []*int{nil, &one, nil, &two},
+		Items:  []*Item{{Name: "a"}, nil, {Name: "c"}},
+		Nested: [][]*int{{nil, &one}},
+		Arr:    [3]*string{nil, nil, &s},
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"ints[1]":       {"1"},
+		"ints[3]":       {"2"},
+		"items[0].name": {"a"},
+		"items[2].name": {"c"},
+		"nested[0][1]":  {"1"},
+		"arr[2]":        {"s"},
+	})
+
+	encoder.SetNilElementPlaceholder("")
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"ints[0]":       {""},
+		"ints[1]":       {"1"},
+		"ints[2]":       {""},
+		"ints[3]":       {"2"},
+		"items[0].name": {"a"},
+		"items[1]":      {""},
+		"items[2].name": {"c"},
+		"nested[0][0]":  {""},
+		"nested[0][1]":  {"1"},
+		"arr[0]":        {""},
+		"arr[1]":        {""},
+		"arr[2]":        {"s"},
+	})
+
+	var decoded Test
+
+	err = NewDecoder().Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded.Ints, tst.Ints)
+	Equal(t, decoded.Items, tst.Items)
+	Equal(t, decoded.Nested, tst.Nested)
+}
+
+func TestEncoder_SetPluralizeSliceKeys(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Tag      []string  `form:"tag"`
+		Box      [2]int    `form:"box"`
+		Category []string  `form:"category"`
+		Key      *[]string `form:"key"`
+		Name     string    `form:"name"`
+	}
+
+	tst := Test{
+		Tag:      []string{"a", "b"},
+		Box:      [2]int{1, 2},
+		Category: []string{"c"},
+		Key:      &[]string{"k"},
+		Name:     "n",
+	}
+
+	encoder := NewEncoder()
+	encoder.SetPluralizeSliceKeys(true)
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"tags":       {"a", "b"},
+		"boxes":      {"1", "2"},
+		"categories": {"c"},
+		"keys":       {"k"},
+		"name":       {"n"},
+	})
+
+	decoder := NewDecoder()
+	decoder.SetPluralizeSliceKeys(true)
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	encoder.SetPluralizeFunc(func(name string) string { return name + "List" })
+
+	values, err = encoder.Encode(Test{Tag: []string{"a"}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"tagList": {"a"}, "boxList": {"0", "0"}, "name": {""}})
+
+	encoder.SetPluralizeSliceKeys(false)
+
+	values, err = encoder.Encode(Test{Tag: []string{"a"}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"tag": {"a"}, "box": {"0", "0"}, "name": {""}})
+}
+
+func TestEncoder_JSONArray(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		IDs    []int    `form:"ids,jsonarray"`
+		Names  []string `form:"names,jsonarray"`
+		Pair   [2]int   `form:"pair,jsonarray"`
+		Empty  []string `form:"empty,jsonarray"`
+		Nil    []string `form:"nil,jsonarray"`
+		PtrIDs *[]int   `form:"ptrIds,jsonarray"`
+		Plain  []string `form:"plain"`
+	}
+
+	tst := Test{
+		IDs:    []int{1, 2, 3},
+		Names:  []string{"a", "b,c"},
+		Pair:   [2]int{4, 5},
+		Empty:  []string{},
+		PtrIDs: &[]int{6},
+		Plain:  []string{"x", "y"},
+	}
+
+	values, err := NewEncoder().Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"ids":    {"[1,2,3]"},
+		"names":  {`["a","b,c"]`},
+		"pair":   {"[4,5]"},
+		"empty":  {"[]"},
+		"ptrIds": {"[6]"},
+		"plain":  {"x", "y"},
+	})
+
+	var decoded Test
+
+	err = NewDecoder().Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	err = NewDecoder().Decode(&decoded, url.Values{"ids": {"[1,"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.(DecodeErrors)["ids"].(*DecodeError).Value, "[1,")
+}
+
+func TestEncoder_RegisterMapKeyFunc(t *testing.T) {
+	t.Parallel()
+
+	type Point struct {
+		X, Y int
+	}
+
+	type Test struct {
+		Points map[Point]int `form:"points"`
+	}
+
+	tst := Test{Points: map[Point]int{{X: 1, Y: 2}: 3, {X: 4, Y: 5}: 6}}
+
+	encoder := NewEncoder()
+
+	_, err := encoder.Encode(tst)
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["points"].Error(), "unsupported map key type 'form.Point' namespace 'points', "+
+		"register key serializer with RegisterMapKeyFunc")
+
+	encoder.RegisterMapKeyFunc(Point{}, func(x interface{}) (string, error) {
+		p := x.(Point)
+
+		return strconv.Itoa(p.X) + ":" + strconv.Itoa(p.Y), nil
+	})
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"points[1:2]": {"3"}, "points[4:5]": {"6"}})
+
+	encoder.RegisterMapKeyFunc(Point{}, func(x interface{}) (string, error) {
+		return "", errors.New("bad point")
+	})
+
+	_, err = encoder.Encode(tst)
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["points"].Error(), "bad point")
+}
+
+func TestEncoder_SetNumberLocale(t *testing.T) {
+	t.Parallel()
+
+	format := func(f float64) string {
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		intPart, frac := s, ""
+
+		if i := strings.IndexByte(s, '.'); i != -1 {
+			intPart, frac = s[:i], s[i:]
+		}
+
+		sign := ""
+		if strings.HasPrefix(intPart, "-") {
+			sign, intPart = "-", intPart[1:]
+		}
+
+		for i := len(intPart) - 3; i > 0; i -= 3 {
+			intPart = intPart[:i] + "," + intPart[i:]
+		}
+
+		return sign + intPart + frac
+	}
+
+	parse := func(s string) (float64, error) {
+		return strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	}
+
+	type Test struct {
+		Price  float64   `form:"price"`
+		Count  int       `form:"count"`
+		Small  uint8     `form:"small"`
+		Delta  int       `form:"delta"`
+		Name   string    `form:"name"`
+		Prices []float32 `form:"prices"`
+	}
+
+	tst := Test{Price: 1234.56, Count: 1234567, Small: 12, Delta: -1000, Name: "1000", Prices: []float32{1000.5}}
+
+	encoder := NewEncoder()
+	encoder.SetNumberLocale(format)
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"price":  {"1,234.56"},
+		"count":  {"1,234,567"},
+		"small":  {"12"},
+		"delta":  {"-1,000"},
+		"name":   {"1000"},
+		"prices": {"1,000.5"},
+	})
+
+	decoder := NewDecoder()
+	decoder.SetNumberLocale(parse)
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	err = decoder.Decode(&decoded, url.Values{"count": {"1,5"}, "small": {"1,000"}, "price": {"x"}, "delta": {"1.5"}})
+	NotEqual(t, err, nil)
+
+	errs := err.(DecodeErrors)
+	Equal(t, len(errs), 3)
+	Equal(t, errs["small"].Error(), "invalid unsigned integer value '1,000' type 'uint8' namespace 'small'")
+	Equal(t, errs["delta"].Error(), "invalid integer value '1.5' type 'int' namespace 'delta'")
+	NotEqual(t, errs["price"], nil)
+}
+
+func TestEncoder_SetReverseFieldOrder(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		X int `form:"x"`
+		Y int `form:"y"`
+	}
+
+	type Test struct {
+		A     string `form:"a"`
+		Inner Inner  `form:"inner"`
+		C     string `form:"c"`
+	}
+
+	tst := Test{A: "1", Inner: Inner{X: 2, Y: 3}, C: "4"}
+
+	encoder := NewEncoder()
+
+	values, columns, err := encoder.EncodeWithColumns(tst)
+	Equal(t, err, nil)
+	Equal(t, columns, []string{"a", "inner.x", "inner.y", "c"})
+
+	encoder.SetReverseFieldOrder(true)
+
+	reversed, columns, err := encoder.EncodeWithColumns(tst)
+	Equal(t, err, nil)
+	Equal(t, columns, []string{"c", "inner.y", "inner.x", "a"})
+	Equal(t, reversed, values)
+
+	var b bytes.Buffer
+
+	Equal(t, encoder.EncodeToWriter(&b, tst), nil)
+	Equal(t, b.String(), "c=4&inner.y=3&inner.x=2&a=1")
+}
+
+type textPoint struct {
+	X, Y int
+}
+
+func (p textPoint) MarshalText() ([]byte, error) {
+	if p.X < 0 {
+		return nil, errors.New("negative x")
+	}
+
+	return []byte(strconv.Itoa(p.X) + ":" + strconv.Itoa(p.Y)), nil
+}
+
+func (p *textPoint) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d:%d", &p.X, &p.Y)
+
+	return err
+}
+
+func TestEncoder_Encode_topLevelTextMarshaler(t *testing.T) {
+	t.Parallel()
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(textPoint{X: 1, Y: 2})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"": {"1:2"}})
+
+	values, err = encoder.Encode(&textPoint{X: 3, Y: 4})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"": {"3:4"}})
+
+	var p textPoint
+
+	err = NewDecoder().Decode(&p, values)
+	Equal(t, err, nil)
+	Equal(t, p, textPoint{X: 3, Y: 4})
+
+	values, err = encoder.Encode(textMarshaler("a"))
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"": {"marshaled:a"}})
+
+	_, err = encoder.Encode(textPoint{X: -1})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace: ERROR:negative x")
+
+	encoder.SetRootKey("point")
+
+	values, err = encoder.Encode(textPoint{X: 1, Y: 2})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"point": {"1:2"}})
+}
+
+func TestEncoder_SetContextualNameFunc(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `form:"city"`
+	}
+
+	type User struct {
+		Name    string  `form:"name"`
+		Address Address `form:"address"`
+	}
+
+	type Company struct {
+		Name    string  `form:"name"`
+		Address Address `form:"address"`
+	}
+
+	type Test struct {
+		User    User    `form:"user"`
+		Company Company `form:"company"`
+	}
+
+	calls := 0
+	nameFn := func(parentType reflect.Type, goField string, tag string) string {
+		calls++
+
+		if goField != "Address" {
+			return ""
+		}
+
+		switch parentType {
+		case reflect.TypeOf(User{}):
+			return "home"
+		case reflect.TypeOf(Company{}):
+			return "office"
+		}
+
+		return tag
+	}
+
+	tst := Test{
+		User:    User{Name: "u", Address: Address{City: "a"}},
+		Company: Company{Name: "c", Address: Address{City: "b"}},
+	}
+
+	encoder := NewEncoder()
+	encoder.SetContextualNameFunc(nameFn)
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"user.name":           {"u"},
+		"user.home.city":      {"a"},
+		"company.name":        {"c"},
+		"company.office.city": {"b"},
+	})
+
+	cached := calls
+
+	_, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, calls, cached)
+
+	decoder := NewDecoder()
+	decoder.SetContextualNameFunc(nameFn)
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+}
+
+func TestEncoder_EncodeForGroups(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		ID       int    `form:"id,required,group=update"`
+		Name     string `form:"name,required,group=create|update"`
+		Password string `form:"password,required,group=create"`
+		Note     string `form:"note"`
+		Email    string `form:"email,required"`
+	}
+
+	encoder := NewEncoder()
+
+	tst := Test{Name: "n", Password: "p", Note: "x", Email: "e"}
+
+	values, err := encoder.EncodeForGroups(tst, "create")
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"n"}, "password": {"p"}, "note": {"x"}, "email": {"e"}})
+
+	_, err = encoder.EncodeForGroups(tst, "update")
+	NotEqual(t, err, nil)
+
+	errs := err.(EncodeErrors)
+	Equal(t, len(errs), 1)
+	Equal(t, errs["id"].Error(), "required field is empty in group 'update'")
+
+	_, err = encoder.EncodeForGroups(Test{}, "create")
+	NotEqual(t, err, nil)
+
+	errs = err.(EncodeErrors)
+	Equal(t, len(errs), 3)
+	Equal(t, errs["name"].Error(), "required field is empty in group 'create'")
+	Equal(t, errs["password"].Error(), "required field is empty in group 'create'")
+	Equal(t, errs["email"].Error(), "required field is empty")
+
+	values, err = encoder.EncodeForGroups(Test{Note: "x", Email: "e"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"note": {"x"}, "email": {"e"}})
+
+	_, err = encoder.Encode(Test{Email: "e"})
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["id"].Error(), "required field is empty")
+}
+
+func TestEncoder_SetVisibility(t *testing.T) {
+	t.Parallel()
+
+	type Profile struct {
+		Nick  string `form:"nick,visibility=public"`
+		Phone string `form:"phone,visibility=internal"`
+	}
+
+	type Test struct {
+		Name    string  `form:"name"`
+		SSN     string  `form:"ssn,visibility=internal"`
+		Secret  string  `form:"secret,visibility=private"`
+		Odd     string  `form:"odd,visibility=unknown"`
+		Profile Profile `form:"profile"`
+	}
+
+	tst := Test{Name: "n", SSN: "s", Secret: "x", Odd: "o", Profile: Profile{Nick: "k", Phone: "p"}}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, len(values), 6)
+
+	encoder.SetVisibility("public")
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"n"}, "profile.nick": {"k"}})
+
+	encoder.SetVisibility("internal")
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"n"}, "ssn": {"s"}, "profile.nick": {"k"}, "profile.phone": {"p"}})
+
+	encoder.SetVisibilityLevels("public", "internal", "private", "unknown")
+	encoder.SetVisibility("unknown")
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, len(values), 6)
+}
+
+func TestEncoder_SetIdempotencyKeyFunc(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Amount int    `form:"amount"`
+		Key    string `form:"idempotency_key"`
+	}
+
+	n := 0
+	encoder := NewEncoder()
+	encoder.SetIdempotencyKeyFunc("idempotency_key", func() string {
+		n++
+
+		return "key-" + strconv.Itoa(n)
+	})
+
+	values, columns, err := encoder.EncodeWithColumns(Test{Amount: 1, Key: "stale"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"amount": {"1"}, "idempotency_key": {"key-1"}})
+	Equal(t, columns, []string{"amount", "idempotency_key"})
+
+	values, err = encoder.Encode(struct {
+		Amount int `form:"amount"`
+	}{Amount: 2})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"amount": {"2"}, "idempotency_key": {"key-2"}})
+
+	var b strings.Builder
+
+	Equal(t, encoder.EncodeToStringBuilder(&b, Test{Amount: 3}), nil)
+	Equal(t, b.String(), "amount=3&idempotency_key=key-3")
+
+	encoder.SetIdempotencyKeyFunc("", nil)
+
+	values, err = encoder.Encode(Test{Amount: 4})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"amount": {"4"}, "idempotency_key": {""}})
+}
+
+func TestEncoder_RegisterTransform(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Card  string   `form:"card,transform=mask"`
+		PIN   *int     `form:"pin,transform=mask"`
+		Code  string   `form:"code,transform=upper"`
+		Codes []string `form:"codes,transform=upper"`
+		Name  string   `form:"name"`
+		Empty *int     `form:"empty,transform=mask"`
+	}
+
+	encoder := NewEncoder()
+	encoder.RegisterTransform("mask", func(v reflect.Value) (string, error) {
+		s := fmt.Sprint(v.Interface())
+		if len(s) <= 4 {
+			return strings.Repeat("*", len(s)), nil
+		}
+
+		return strings.Repeat("*", len(s)-4) + s[len(s)-4:], nil
+	})
+	encoder.RegisterTransform("upper", func(v reflect.Value) (string, error) {
+		if v.Kind() != reflect.String {
+			return "", fmt.Errorf("upper: unsupported kind %s", v.Kind())
+		}
+
+		return strings.ToUpper(v.String()), nil
+	})
+
+	pin := 1234
+
+	values, err := encoder.Encode(struct {
+		Card string `form:"card,transform=mask"`
+		PIN  *int   `form:"pin,transform=mask"`
+		Code string `form:"code,transform=upper"`
+		Name string `form:"name"`
+	}{Card: "4111111111111111", PIN: &pin, Code: "abc", Name: "n"})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"card": {"************1111"}, "pin": {"****"}, "code": {"ABC"}, "name": {"n"}})
+
+	_, err = encoder.Encode(Test{Codes: []string{"a"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["codes"].Error(), "upper: unsupported kind slice")
+
+	_, err = encoder.Encode(struct {
+		Card string `form:"card,transform=unknown"`
+	}{})
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["card"].Error(), "unknown transform 'unknown' namespace 'card'")
+}
+
+func TestEncoder_EmptyAs(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		IDs []int `form:"ids,emptyas=ids[]"`
+	}
+
+	type Test struct {
+		Tags  []string  `form:"tags,emptyas=tags[]"`
+		Ptr   *[]string `form:"ptr,emptyas=ptr[]"`
+		Inner Inner     `form:"inner"`
+		Plain []string  `form:"plain"`
+	}
+
+	encoder := NewEncoder()
+	decoder := NewDecoder()
+
+	tst := Test{Tags: []string{}, Ptr: &[]string{}, Inner: Inner{IDs: []int{}}, Plain: []string{}}
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"tags[]": {""}, "ptr[]": {""}, "inner.ids[]": {""}})
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, Test{Tags: []string{}, Ptr: &[]string{}, Inner: Inner{IDs: []int{}}})
+
+	values, err = encoder.Encode(Test{})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{})
+
+	decoded = Test{}
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, Test{})
+
+	values, err = encoder.Encode(Test{Tags: []string{"a", "b"}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"tags": {"a", "b"}})
+
+	decoded = Test{}
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, Test{Tags: []string{"a", "b"}})
+}
+
+func TestEncoder_SetDedupeStructSlices(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		ID   int      `form:"id"`
+		Tags []string `form:"tags"`
+	}
+
+	type Test struct {
+		Items []Item   `form:"items"`
+		Ptrs  []*Item  `form:"ptrs"`
+		Names []string `form:"names"`
+	}
+
+	tst := Test{
+		Items: []Item{{ID: 1, Tags: []string{"a"}}, {ID: 2}, {ID: 1, Tags: []string{"a"}}, {ID: 1, Tags: []string{"b"}}},
+		Ptrs:  []*Item{{ID: 3}, {ID: 3}},
+		Names: []string{"x", "x"},
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, len(values["items[2].id"]), 1)
+
+	encoder.SetDedupeStructSlices(true)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"items[0].id":      {"1"},
+		"items[0].tags[0]": {"a"},
+		"items[1].id":      {"2"},
+		"items[2].id":      {"1"},
+		"items[2].tags[0]": {"b"},
+		"ptrs[0].id":       {"3"},
+		"names":            {"x", "x"},
+	})
+}
+
+func TestEncoder_SetFieldPriority(t *testing.T) {
+	t.Parallel()
+
+	type User struct {
+		Name  string `form:"name"`
+		Email string `form:"email"`
+	}
+
+	type Test struct {
+		ID      int      `form:"id"`
+		Comment string   `form:"comment"`
+		User    User     `form:"user"`
+		Tags    []string `form:"tags"`
+		Title   string   `form:"title"`
+	}
+
+	tst := Test{ID: 1, Comment: "c", User: User{Name: "n", Email: "e"}, Tags: []string{"a"}, Title: "t"}
+
+	encoder := NewEncoder()
+	encoder.SetFieldPriority(map[string]int{
+		"title":      -2,
+		"user":       -1,
+		"user.email": -3,
+		"comment":    1,
+	})
+
+	values, columns, err := encoder.EncodeWithColumns(tst)
+	Equal(t, err, nil)
+	Equal(t, len(values), 6)
+	Equal(t, columns, []string{"user.email", "title", "user.name", "id", "tags", "comment"})
+
+	var b bytes.Buffer
+
+	Equal(t, encoder.EncodeToWriter(&b, tst), nil)
+	Equal(t, b.String(), "user.email=e&title=t&user.name=n&id=1&tags=a&comment=c")
+}
+
+func TestEncoder_SetAutoDiscriminator(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Shapes []testShape `form:"shapes"`
+		Any    interface{} `form:"any"`
+		Time   interface{} `form:"time"`
+	}
+
+	tst := Test{
+		Shapes: []testShape{testCircle{Radius: 1}, &testSquare{Side: 2}, testCircle{Radius: 3}},
+		Any:    testCircle{Radius: 4},
+		Time:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	e := NewEncoder()
+	e.SetAutoDiscriminator(true)
+
+	values, err := e.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"shapes[0]._type":  {"testCircle"},
+		"shapes[0].radius": {"1"},
+		"shapes[1]._type":  {"testSquare"},
+		"shapes[1].side":   {"2"},
+		"shapes[2]._type":  {"testCircle"},
+		"shapes[2].radius": {"3"},
+		"any._type":        {"testCircle"},
+		"any.radius":       {"4"},
+		"time":             {"2020-01-02T00:00:00Z"},
+	})
+
+	d := NewDecoder()
+	err = d.RegisterTypesByName(testCircle{}, &testSquare{})
+	Equal(t, err, nil)
+
+	var decoded Test
+
+	err = d.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded.Shapes, tst.Shapes)
+	Equal(t, decoded.Any, tst.Any)
+
+	// same type can be registered again, names of different types must not collide
+	err = d.RegisterTypesByName(&testCircle{})
+	Equal(t, err, nil)
+
+	type testCircle struct {
+		Diameter float64 `form:"diameter"`
+	}
+
+	err = d.RegisterTypesByName(testCircle{})
+	NotEqual(t, err, nil)
+	Equal(t, strings.HasPrefix(err.Error(), "form: type name 'testCircle' of "), true)
+
+	err = NewDecoder().RegisterTypesByName(testSquare{}, testCircle{}, &testCircle{})
+	Equal(t, err, nil)
+}
+
+func TestEncoder_RegisterBigIntBase(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		ID  big.Int    `form:"id"`
+		Ptr *big.Int   `form:"ptr"`
+		IDs []*big.Int `form:"ids"`
+	}
+
+	encoder := NewEncoder()
+	decoder := NewDecoder()
+
+	NotEqual(t, encoder.RegisterBigIntBase(63), nil)
+	NotEqual(t, decoder.RegisterBigIntBase(1), nil)
+	Equal(t, encoder.RegisterBigIntBase(62), nil)
+	Equal(t, decoder.RegisterBigIntBase(62), nil)
+
+	values, err := encoder.Encode(Test{ID: *big.NewInt(61), Ptr: big.NewInt(-62), IDs: []*big.Int{big.NewInt(0)}})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"id": {"Z"}, "ptr": {"-10"}, "ids[0]": {"0"}})
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	for _, s := range []string{"0", "1", "-1", "61", "62", "-3843", "3844", "9223372036854775807", huge.String()} {
+		n, _ := new(big.Int).SetString(s, 10)
+		tst := Test{ID: *n, Ptr: n}
+
+		values, err := encoder.Encode(tst)
+		Equal(t, err, nil)
+
+		var decoded Test
+
+		err = decoder.Decode(&decoded, values)
+		Equal(t, err, nil)
+		Equal(t, decoded.ID.String(), s)
+		Equal(t, decoded.Ptr.String(), s)
+	}
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, url.Values{"id": {"a_b"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.Error(), "Field Namespace:id ERROR:invalid base 62 integer value 'a_b'")
+}
+
+func TestEncoder_SetDebugComments(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name string `form:"name"`
+		Age  int    `form:"age"`
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(Test{Name: "n", Age: 1})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"n"}, "age": {"1"}})
+
+	encoder.SetDebugComments(true)
+
+	values, columns, err := encoder.EncodeWithColumns(&Test{Name: "n", Age: 1})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"_debug_path": {"form.Test"}, "name": {"n"}, "age": {"1"}})
+	Equal(t, columns, []string{"_debug_path", "name", "age"})
+
+	var b strings.Builder
+
+	Equal(t, encoder.EncodeToStringBuilder(&b, Test{Name: "n", Age: 1}), nil)
+	Equal(t, b.String(), "_debug_path=form.Test&name=n&age=1")
+
+	var decoded Test
+
+	err = NewDecoder().Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, Test{Name: "n", Age: 1})
+}
+
+type jsonPoint struct {
+	X, Y int
+}
+
+func (p jsonPoint) MarshalJSON() ([]byte, error) {
+	if p.X < 0 {
+		return nil, errors.New("negative x")
+	}
+
+	return []byte(fmt.Sprintf("[%d,%d]", p.X, p.Y)), nil
+}
+
+func (p *jsonPoint) UnmarshalJSON(b []byte) error {
+	var xy [2]int
+
+	if err := json.Unmarshal(b, &xy); err != nil {
+		return err
+	}
+
+	p.X, p.Y = xy[0], xy[1]
+
+	return nil
+}
+
+func TestEncoder_SetUseJSONMarshaler(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Point  jsonPoint     `form:"point"`
+		Ptr    *jsonPoint    `form:"ptr"`
+		Points []jsonPoint   `form:"points"`
+		Time   time.Time     `form:"time"`
+		Text   textMarshaler `form:"text"`
+	}
+
+	tst := Test{
+		Point:  jsonPoint{X: 1, Y: 2},
+		Ptr:    &jsonPoint{X: 3, Y: 4},
+		Points: []jsonPoint{{X: 5, Y: 6}},
+		Time:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		Text:   "t",
+	}
+
+	encoder := NewEncoder()
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values["point.X"], []string{"1"})
+
+	encoder.SetUseJSONMarshaler(true)
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"point":     {"[1,2]"},
+		"ptr":       {"[3,4]"},
+		"points[0]": {"[5,6]"},
+		"time":      {"2020-01-02T00:00:00Z"},
+		"text":      {"marshaled:t"},
+	})
+
+	decoder := NewDecoder()
+	decoder.SetUseJSONUnmarshaler(true)
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded.Point, tst.Point)
+	Equal(t, decoded.Ptr, tst.Ptr)
+	Equal(t, decoded.Points, tst.Points)
+	Equal(t, decoded.Time, tst.Time)
+
+	err = decoder.Decode(&decoded, url.Values{"point": {"[1,"}})
+	NotEqual(t, err, nil)
+
+	_, err = encoder.Encode(Test{Point: jsonPoint{X: -1}})
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["point"].Error(), "negative x")
+}
+
+func TestEncoder_pointerChains(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		A int `form:"a"`
+	}
+
+	type Test struct {
+		PP    **int    `form:"pp"`
+		PPP   ***Inner `form:"ppp"`
+		PPO   **int    `form:"ppo,omitempty"`
+		PPR   **int    `form:"ppr,required"`
+		Items []**int  `form:"items"`
+	}
+
+	i := 5
+	pi := &i
+	in := &Inner{A: 1}
+	pin := &in
+
+	var (
+		nilInt   *int
+		nilInner *Inner
+	)
+
+	pNilInner := &nilInner
+
+	encoder := NewEncoder()
+	decoder := NewDecoder()
+
+	tst := Test{PP: &pi, PPP: &pin, PPO: &pi, PPR: &pi, Items: []**int{&pi, nil, &nilInt}}
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"pp": {"5"}, "ppp.a": {"1"}, "ppo": {"5"}, "ppr": {"5"}, "items[0]": {"5"}})
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, **decoded.PP, 5)
+	Equal(t, ***decoded.PPP, Inner{A: 1})
+	Equal(t, len(decoded.Items), 1)
+	Equal(t, **decoded.Items[0], 5)
+
+	_, err = encoder.Encode(Test{PP: &nilInt, PPO: &nilInt, PPR: &nilInt})
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["ppr"].Error(), "required field is empty")
+
+	encoder.SetNilStructMarker("nil")
+	encoder.SetNilElementPlaceholder("")
+
+	for _, ppp := range []***Inner{new(**Inner), &pNilInner} {
+		values, err = encoder.Encode(Test{PPP: ppp, PPR: &pi, Items: []**int{nil, &nilInt, &pi}})
+		Equal(t, err, nil)
+		Equal(t, values, url.Values{"ppp": {"nil"}, "ppr": {"5"}, "items[0]": {""}, "items[1]": {""}, "items[2]": {"5"}})
+	}
+
+	values, err = encoder.Encode(Test{PPR: &pi})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"ppp": {"nil"}, "ppr": {"5"}})
+}
+
+func TestEncoder_repeatedParentIndexStyle(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		Name string `form:"name"`
+		ID   int    `form:"id"`
+	}
+
+	type Test struct {
+		Items []Item   `form:"items"`
+		Ptrs  []*Item  `form:"ptrs"`
+		Tags  []string `form:"tags"`
+	}
+
+	encoder := NewEncoder()
+	encoder.SetIndexStyle(IndexStyleRepeatedParent)
+
+	decoder := NewDecoder()
+	decoder.SetGroupRepeatedParents(true)
+
+	tst := Test{
+		Items: []Item{{Name: "a", ID: 1}, {Name: "b", ID: 2}},
+		Ptrs:  []*Item{{Name: "c", ID: 3}},
+		Tags:  []string{"x", "y"},
+	}
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"items.name": {"a", "b"},
+		"items.id":   {"1", "2"},
+		"ptrs.name":  {"c"},
+		"ptrs.id":    {"3"},
+		"tags":       {"x", "y"},
+	})
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	// indexed keys are decoded as usual
+	var indexed Test
+
+	err = decoder.Decode(&indexed, url.Values{"items[1].name": {"b"}})
+	Equal(t, err, nil)
+	Equal(t, indexed.Items, []Item{{}, {Name: "b"}})
+
+	// grouping is disabled by default
+	var ungrouped Test
+
+	err = NewDecoder().Decode(&ungrouped, values)
+	Equal(t, err, nil)
+	Equal(t, len(ungrouped.Items), 0)
+
+	err = decoder.Decode(&decoded, url.Values{"items.id": {"1", "x"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.(DecodeErrors)["items.id"].(*DecodeError).Value, "x")
+
+	// grouped elements are appended to existing ones
+	existing := Test{Items: []Item{{Name: "z"}}}
+
+	err = decoder.Decode(&existing, url.Values{"items.name": {"a", "b"}})
+	Equal(t, err, nil)
+	Equal(t, existing.Items, []Item{{Name: "z"}, {Name: "a"}, {Name: "b"}})
+
+	populated, err := decoder.DecodePopulated(&Test{}, values)
+	Equal(t, err, nil)
+	Equal(t, populated, []string{"items", "items.id", "items.name", "ptrs", "ptrs.id", "ptrs.name", "tags"})
+
+	// limits of parent decoder apply to grouped elements
+	type Node struct {
+		ID   int     `form:"id"`
+		Next *Node   `form:"next"`
+		Kids []*Node `form:"kids"`
+	}
+
+	limited := NewDecoder()
+	limited.SetGroupRepeatedParents(true)
+	limited.SetMaxAllocDepth(2)
+
+	err = limited.Decode(&Node{}, url.Values{"next.kids.next.id": {"1", "2"}})
+	NotEqual(t, err, nil)
+	NotEqual(t, err.(DecodeErrors)["next.kids.next"], nil)
+}
+
+func TestEncoder_SetEncryptFunc(t *testing.T) {
+	t.Parallel()
+
+	type Secret struct {
+		PIN int `form:"pin"`
+	}
+
+	type Test struct {
+		Name   string   `form:"name"`
+		SSN    string   `form:"ssn,encrypt"`
+		Codes  []string `form:"codes,encrypt"`
+		Secret Secret   `form:"secret,encrypt"`
+	}
+
+	encrypt := func(s string) (string, error) {
+		if s == "fail" {
+			return "", errors.New("cipher failure")
+		}
+
+		return "enc:" + base64.StdEncoding.EncodeToString([]byte(s)), nil
+	}
+
+	decrypt := func(s string) (string, error) {
+		if !strings.HasPrefix(s, "enc:") {
+			return "", errors.New("invalid ciphertext")
+		}
+
+		b, err := base64.StdEncoding.DecodeString(s[len("enc:"):])
+
+		return string(b), err
+	}
+
+	encoder := NewEncoder()
+	encoder.SetEncryptFunc(encrypt)
+
+	decoder := NewDecoder()
+	decoder.SetDecryptFunc(decrypt)
+
+	tst := Test{Name: "John", SSN: "123-45-6789", Codes: []string{"a", "b"}, Secret: Secret{PIN: 42}}
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name":       {"John"},
+		"ssn":        {"enc:MTIzLTQ1LTY3ODk="},
+		"codes":      {"enc:YQ==", "enc:Yg=="},
+		"secret.pin": {"enc:NDI="},
+	})
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded, tst)
+
+	// input values are not changed by decryption
+	Equal(t, values["ssn"], []string{"enc:MTIzLTQ1LTY3ODk="})
+
+	_, err = encoder.Encode(Test{SSN: "fail"})
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["ssn"].Error(), "cipher failure")
+
+	_, err = NewEncoder().Encode(tst)
+	NotEqual(t, err, nil)
+	Equal(t, err.(EncodeErrors)["ssn"].Error(), "missing encrypt func for field with encrypt tag option namespace 'ssn'")
+
+	err = decoder.Decode(&decoded, url.Values{"ssn": {"plain"}})
+	NotEqual(t, err, nil)
+	Equal(t, err.(DecodeErrors)["ssn"].(*DecodeError).Value, "plain")
+
+	// values are encrypted and decrypted once for nested and shared encrypted fields
+	type Inner struct {
+		PIN  int    `form:"pin,encrypt"`
+		Code string `form:"code"`
+	}
+
+	type Base struct {
+		Name string `form:"name,encrypt"`
+	}
+
+	type Nested struct {
+		Base
+		Name string `form:"name,encrypt"`
+		In   Inner  `form:"in,encrypt"`
+	}
+
+	nested := Nested{Base: Base{Name: "base"}, Name: "top", In: Inner{PIN: 7, Code: "x"}}
+
+	values, err = encoder.Encode(nested)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{
+		"name":    {"enc:YmFzZQ==", "enc:dG9w"},
+		"in.pin":  {"enc:Nw=="},
+		"in.code": {"enc:eA=="},
+	})
+
+	var decodedNested Nested
+
+	err = decoder.Decode(&decodedNested, values)
+	Equal(t, err, nil)
+	Equal(t, decodedNested.In, nested.In)
+	Equal(t, decodedNested.Name, "base")
+	Equal(t, decodedNested.Base.Name, "base")
+}
+
+func TestEncoder_SetStringThreeState(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name  string  `form:"name"`
+		Title *string `form:"title"`
+		Note  *string `form:"note"`
+		Text  *string `form:"text"`
+	}
+
+	empty, text := "", "hello"
+
+	encoder := NewEncoder()
+	encoder.SetStringThreeState(true, "null")
+
+	decoder := NewDecoder()
+	decoder.SetStringThreeState(true, "null")
+
+	tst := Test{Name: "", Title: &empty, Note: nil, Text: &text}
+
+	values, err := encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {"null"}, "title": {"null"}, "text": {"hello"}})
+
+	var decoded Test
+
+	err = decoder.Decode(&decoded, values)
+	Equal(t, err, nil)
+	Equal(t, decoded.Name, "")
+	Equal(t, *decoded.Title, "")
+	Equal(t, decoded.Note, (*string)(nil))
+	Equal(t, *decoded.Text, "hello")
+
+	tst.Name = "John"
+
+	values, err = encoder.Encode(tst)
+	Equal(t, err, nil)
+	Equal(t, values["name"], []string{"John"})
+
+	// disabled mode emits empty values
+	values, err = NewEncoder().Encode(Test{Title: &empty})
+	Equal(t, err, nil)
+	Equal(t, values, url.Values{"name": {""}, "title": {""}})
+
+	decoded = Test{}
+
+	err = NewDecoder().Decode(&decoded, url.Values{"title": {"null"}})
+	Equal(t, err, nil)
+	Equal(t, *decoded.Title, "null")
+}
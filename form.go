@@ -11,6 +11,11 @@ const (
 	ignore             = "-"
 	fieldNS            = "Field Namespace:"
 	errorText          = " ERROR:"
+	sharedPointerRef   = "@ref:"
+	discriminatorKey   = "_type"
+	debugPathKey       = "_debug_path"
+	splitDateLayout    = "2006-01-02"
+	splitTimeLayout    = "15:04"
 )
 
 var timeType = reflect.TypeOf(time.Time{})
@@ -29,6 +34,99 @@ const (
 	ModeExplicit
 )
 
+// SliceLimitMode specifies how encoder handles slices that exceed
+// the limit of field `max` tag option, e.g. `form:"items,max=100"`.
+type SliceLimitMode uint8
+
+const (
+	// SliceLimitTruncate encodes only first allowed number of items.
+	SliceLimitTruncate SliceLimitMode = iota
+
+	// SliceLimitError fails encoding of a field with too many items.
+	SliceLimitError
+)
+
+// IndexStyle specifies how encoder names keys of slice and array elements.
+type IndexStyle uint8
+
+const (
+	// IndexStyleAuto repeats the key for elements of struct fields, e.g. "tags=a&tags=b",
+	// and indexes elements of nested slices and other containers, e.g. "m[k][0]=a".
+	IndexStyleAuto IndexStyle = iota
+
+	// IndexStyleIndexed indexes all elements, e.g. "tags[0]=a&tags[1]=b".
+	IndexStyleIndexed
+
+	// IndexStyleRepeated repeats the key for elements at any depth, including
+	// slices in map values, e.g. "m[k]=a&m[k]=b".
+	IndexStyleRepeated
+
+	// IndexStyleRepeatedParent repeats the key of every field of struct elements without element index,
+	// e.g. "items.name=a&items.name=b", other elements are named as with IndexStyleAuto.
+	//
+	// Such keys are ambiguous, elements are only restored by position with Decoder.SetGroupRepeatedParents
+	// and only if every element emits the same set of scalar fields: nil elements, empty fields with
+	// omitempty and nested slices shift values to wrong elements.
+	IndexStyleRepeatedParent
+)
+
+// RequiredMode specifies how encoder handles empty fields with `required` tag option.
+type RequiredMode uint8
+
+const (
+	// RequiredError fails encoding of an empty required field.
+	RequiredError RequiredMode = iota
+
+	// RequiredPlaceholder encodes an empty required field as a placeholder value,
+	// see Encoder.SetRequiredPlaceholder.
+	RequiredPlaceholder
+
+	// RequiredWarn encodes an empty required field as is and reports a warning,
+	// see Encoder.SetWarnFunc.
+	RequiredWarn
+)
+
+// SharedPointerMode specifies how encoder handles multiple pointers to the same struct.
+type SharedPointerMode uint8
+
+const (
+	// SharedPointerDuplicate encodes pointed struct for every pointer.
+	SharedPointerDuplicate SharedPointerMode = iota
+
+	// SharedPointerReference encodes pointed struct only once, following
+	// pointers are encoded as a back-reference to namespace of first occurrence.
+	// eg. type A struct { First, Second *B }
+	//     encode results: url.Values{"First.Field":[]string{"B FieldVal"}, "Second":[]string{"@ref:First"}}
+	SharedPointerReference
+)
+
+// ArrayOverflowMode specifies how decoder handles values that do not fit into a fixed size array.
+type ArrayOverflowMode uint8
+
+const (
+	// ArrayOverflowIgnore ignores values over array capacity, a warning is reported with Decoder.SetWarnFunc.
+	ArrayOverflowIgnore ArrayOverflowMode = iota
+
+	// ArrayOverflowError fails decoding of an array field with too many values or an out of bounds index.
+	ArrayOverflowError
+)
+
+// DefaultsMode specifies how decoder applies values of `default=` tag option, e.g. `form:"limit,default=10"`.
+type DefaultsMode uint8
+
+const (
+	// DefaultsIgnore does not apply defaults, fields missing in input keep their values.
+	DefaultsIgnore DefaultsMode = iota
+
+	// DefaultsFirst sets defaults before applying input, so a default remains if input
+	// value is empty or invalid, and present valid input value overrides it.
+	DefaultsFirst
+
+	// DefaultsAbsent sets defaults only for fields without a key in input,
+	// a present key, even with empty value, keeps default from being applied.
+	DefaultsAbsent
+)
+
 // AnonymousMode specifies how data should be rolled up
 // or separated from anonymous structs.
 type AnonymousMode uint8
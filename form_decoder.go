@@ -2,16 +2,26 @@ package form
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // DecodeFunc allows for registering/overriding types to be parsed.
 type DecodeFunc func(string) (interface{}, error)
 
-// DecodeErrors is a map of errors encountered during form decoding.
+// FallbackDecodeFunc decodes raw value into target of a type that has no registered or built-in decoder.
+type FallbackDecodeFunc func(val string, v reflect.Value) error
+
+// DecodeErrors is a map of errors encountered during form decoding,
+// errors are of *DecodeError type keyed by namespace.
 type DecodeErrors map[string]error
 
 func (d DecodeErrors) Error() string {
@@ -28,6 +38,51 @@ func (d DecodeErrors) Error() string {
 	return strings.TrimSpace(buff.String())
 }
 
+// As finds the first error in namespace order that matches target, it allows errors.As
+// to find *DecodeError or an error type of a cause.
+func (d DecodeErrors) As(target interface{}) bool {
+	keys := make([]string, 0, len(d))
+
+	for k := range d {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if errors.As(d[k], target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DecodeError describes a failure to decode a value.
+type DecodeError struct {
+	// Namespace is a key of the value, e.g. "items[0].name".
+	Namespace string
+
+	// Value is an input value that failed to decode, it is empty if there was no value.
+	Value string
+
+	// Type is a type of the destination field, it is nil if the failure is not related to a single field.
+	Type reflect.Type
+
+	// Cause is the underlying error.
+	Cause error
+}
+
+// Error returns the message of the cause.
+func (e *DecodeError) Error() string {
+	return e.Cause.Error()
+}
+
+// Unwrap returns the cause.
+func (e *DecodeError) Unwrap() error {
+	return e.Cause
+}
+
 // An InvalidDecoderError describes an invalid argument passed to Decode.
 // (The argument passed to Decode must be a non-nil pointer.)
 type InvalidDecoderError struct {
@@ -62,15 +117,48 @@ type dataMap []*recursiveData
 
 // Decoder is the main decode instance.
 type Decoder struct {
-	tagName         string
-	mode            Mode
-	structCache     *structCacheMap
-	customTypeFuncs map[reflect.Type]DecodeFunc
-	maxArraySize    int
-	dataPool        *sync.Pool
+	tagName            string
+	mode               Mode
+	structCache        *structCacheMap
+	customTypeFuncs    map[reflect.Type]DecodeFunc
+	interfaceTypes     map[string]reflect.Type
+	fallbackFunc       FallbackDecodeFunc
+	maxArraySize       int
+	maxPathDepth       int
+	maxTotalValueBytes int
+	maxAllocDepth      int
+	timeLayouts        []string
+	timeAutoDetect     bool
+	escapeMapKeys      bool
+	mapKeyTransform    func(string) string
+	arrayOverflow      ArrayOverflowMode
+	boolParseFunc      func(string) (bool, error)
+	lenientKeys        bool
+	jsonBelowDepth     int
+	emptyStruct        string
+	nullString         *string
+	caseInsensitive    bool
+	rootKey            string
+	pluralize          func(string) string
+	strictRoundTrip    bool
+	numberLocale       func(string) (float64, error)
+	defaultsMode       DefaultsMode
+	shapeCoercion      bool
+	groupRepeated      bool
+	useJSONUnmarshaler bool
+	versionKey         string
+	checksumKey        string
+	checksumFunc       func([]byte) string
+	sequencePrefix     bool
+	warnFunc           func(namespace, msg string)
+	decryptFunc        func(ciphertext string) (string, error)
+	dataPool           *sync.Pool
 }
 
-const defaultMaxArraySize = 10000
+const (
+	defaultMaxArraySize = 10000
+	defaultMaxPathDepth = 32
+)
 
 // NewDecoder creates a new decoder instance with sane defaults.
 func NewDecoder() *Decoder {
@@ -79,6 +167,8 @@ func NewDecoder() *Decoder {
 		mode:         ModeImplicit,
 		structCache:  newStructCacheMap(),
 		maxArraySize: defaultMaxArraySize,
+		maxPathDepth: defaultMaxPathDepth,
+		timeLayouts:  []string{time.RFC3339},
 	}
 
 	d.dataPool = &sync.Pool{New: func() interface{} {
@@ -115,6 +205,246 @@ func (d *Decoder) SetMaxArraySize(size uint) {
 	d.maxArraySize = int(size)
 }
 
+// SetMaxPathDepth sets maximum nesting depth of a key, that is the number of
+// '[' and '.' in it, e.g. "a[b][c].d" has depth of 3. Values are not decoded
+// if any key is nested deeper, to avoid processing of unusually deep keys.
+//
+// Default is 32.
+func (d *Decoder) SetMaxPathDepth(depth uint) {
+	d.maxPathDepth = int(depth)
+}
+
+// SetMaxTotalValueBytes sets maximum total length in bytes of values bound to fields, decoding fails
+// if the total is larger, to bound processing of unusually large input on public endpoints.
+// Keys that do not match any field are not counted, no more values are bound once the limit is exceeded.
+//
+// Default is 0, which disables the limit.
+func (d *Decoder) SetMaxTotalValueBytes(n int) {
+	d.maxTotalValueBytes = n
+}
+
+// SetMaxAllocDepth sets maximum number of nested struct pointers to allocate along a key path,
+// e.g. "next.next.id" of recursive type Node struct{ Next *Node; ID int } needs allocation depth of 2.
+// Keys that need deeper allocation fail decoding, to bound allocation for recursive struct definitions.
+//
+// Default is 0, which disables the limit.
+func (d *Decoder) SetMaxAllocDepth(n int) {
+	d.maxAllocDepth = n
+}
+
+// SetTimeLayouts sets layouts to parse time.Time values, layouts are tried in the given order
+// and the first successful result is used.
+//
+// Default is time.RFC3339.
+func (d *Decoder) SetTimeLayouts(layouts ...string) {
+	d.timeLayouts = layouts
+}
+
+// SetTimeAutoDetect enables parsing time.Time values as Unix timestamp in seconds
+// if no time layout matches and the value consists of digits only.
+//
+// Please note, that numeric value is ambiguous, e.g. "2024" would be treated as a timestamp
+// rather than a year, unless a matching layout (e.g. "2006") is set with SetTimeLayouts.
+func (d *Decoder) SetTimeAutoDetect(enabled bool) {
+	d.timeAutoDetect = enabled
+}
+
+// SetFallbackToJSONTag enables using `json` field tag (including "-") for fields that have no decoder tag.
+//
+// NOTE: This method is not thread-safe it is intended to be called prior to any parsing.
+func (d *Decoder) SetFallbackToJSONTag(enabled bool) {
+	d.structCache.jsonFallback = enabled
+}
+
+// SetEscapeMapKeys enables unescaping of percent-encoded map keys, e.g. "field[a%2Eb]" for key "a.b",
+// as produced by Encoder with matching SetEscapeMapKeys option.
+func (d *Decoder) SetEscapeMapKeys(enabled bool) {
+	d.escapeMapKeys = enabled
+}
+
+// SetMapKeyTransform sets a function to transform keys of input values before decoding into maps
+// with string keys, e.g. to convert snake_case keys to camelCase. Field names are not affected.
+func (d *Decoder) SetMapKeyTransform(fn func(key string) string) {
+	d.mapKeyTransform = fn
+}
+
+// SetArrayOverflowMode sets how decoder handles values that do not fit into a fixed size array,
+// e.g. "Array[3]" for [3]int.
+//
+// Default is ArrayOverflowIgnore.
+func (d *Decoder) SetArrayOverflowMode(mode ArrayOverflowMode) {
+	d.arrayOverflow = mode
+}
+
+// SetDefaultsMode sets how decoder applies values of `default=` tag option, e.g. `form:"limit,default=10"`.
+//
+// Default is DefaultsIgnore.
+func (d *Decoder) SetDefaultsMode(mode DefaultsMode) {
+	d.defaultsMode = mode
+}
+
+// SetApplyDefaultsFirst enables setting defaults before applying input, it is a shorthand for
+// SetDefaultsMode(DefaultsFirst), disabling it sets DefaultsIgnore.
+func (d *Decoder) SetApplyDefaultsFirst(enabled bool) {
+	d.defaultsMode = DefaultsIgnore
+
+	if enabled {
+		d.defaultsMode = DefaultsFirst
+	}
+}
+
+// SetBoolParseFunc sets a function to parse boolean values at any depth, including map keys,
+// it mirrors Encoder.SetBoolFunc.
+//
+// Default accepts strconv.ParseBool values and also "on", "yes", "ok", "off", "no" and "".
+func (d *Decoder) SetBoolParseFunc(fn func(string) (bool, error)) {
+	d.boolParseFunc = fn
+}
+
+// SetLenientKeyParsing enables best effort parsing of keys with unbalanced brackets,
+// e.g. "field[0" or "field]0[", such brackets are treated as literal characters
+// and a warning is reported to the function of SetWarnFunc.
+//
+// Default is false, such keys fail decoding of slices, arrays and maps.
+func (d *Decoder) SetLenientKeyParsing(enabled bool) {
+	d.lenientKeys = enabled
+}
+
+// SetWarnFunc sets a function to receive warnings about recoverable issues of input values.
+func (d *Decoder) SetWarnFunc(fn func(namespace, msg string)) {
+	d.warnFunc = fn
+}
+
+// SetDecryptFunc sets a function to decrypt values of fields with `encrypt` tag option,
+// it mirrors Encoder.SetEncryptFunc.
+//
+// Fields with `encrypt` tag option fail decoding if function is not set.
+func (d *Decoder) SetDecryptFunc(fn func(ciphertext string) (string, error)) {
+	d.decryptFunc = fn
+}
+
+// SetJSONBelowDepth enables decoding of structs nested deeper than depth levels from JSON
+// of a single key, it mirrors Encoder.SetJSONBelowDepth.
+//
+// Default is 0, which disables JSON decoding.
+func (d *Decoder) SetJSONBelowDepth(depth int) {
+	d.jsonBelowDepth = depth
+}
+
+// SetVerifyChecksum enables verification of a checksum of values under the key, it mirrors
+// Encoder.SetChecksumKey. Values are not decoded if checksum is missing or does not match.
+func (d *Decoder) SetVerifyChecksum(key string, fn func([]byte) string) {
+	d.checksumKey = key
+	d.checksumFunc = fn
+}
+
+// SetSequencePrefix enables stripping of key sequence number prefix, e.g. "001:field",
+// it mirrors Encoder.SetSequencePrefix. Keys without prefix are left as is.
+func (d *Decoder) SetSequencePrefix(enabled bool) {
+	d.sequencePrefix = enabled
+}
+
+// SetEmptyStructMarker sets a value that decodes into a zero struct, it mirrors Encoder.SetEmptyStructMarker,
+// so that a pointer to struct is allocated even if there are no values of struct fields.
+//
+// Default is empty, which disables the marker.
+func (d *Decoder) SetEmptyStructMarker(marker string) {
+	d.emptyStruct = marker
+}
+
+// SetStringThreeState enables decoding of nullLit as empty string, it mirrors Encoder.SetStringThreeState,
+// so that *string is nil if key is absent and points to empty string for nullLit.
+//
+// Disabled by default, nullLit is decoded as is.
+func (d *Decoder) SetStringThreeState(enabled bool, nullLit string) {
+	d.nullString = nil
+
+	if enabled {
+		d.nullString = &nullLit
+	}
+}
+
+// SetCaseInsensitive enables case-insensitive matching of boolean values, e.g. "tRuE" or "YES",
+// and of values of encoding.TextUnmarshaler types, such as enums, that are retried
+// in lower and upper case if the value as is fails to unmarshal.
+func (d *Decoder) SetCaseInsensitive(enabled bool) {
+	d.caseInsensitive = enabled
+}
+
+// SetRootKey sets a key that wraps all values, it mirrors Encoder.SetRootKey.
+func (d *Decoder) SetRootKey(key string) {
+	d.rootKey = key
+}
+
+// SetPluralizeSliceKeys enables pluralized keys of slice and array fields, it mirrors Encoder.SetPluralizeSliceKeys.
+func (d *Decoder) SetPluralizeSliceKeys(enabled bool) {
+	d.pluralize = nil
+
+	if enabled {
+		d.pluralize = pluralize
+	}
+}
+
+// SetPluralizeFunc sets a custom function to pluralize keys of slice and array fields, it mirrors Encoder.SetPluralizeFunc.
+func (d *Decoder) SetPluralizeFunc(fn func(name string) string) {
+	d.pluralize = fn
+}
+
+// SetStrictRoundTrip enables rejecting scalar values that do not format back to the same input after parsing,
+// e.g. "007" for int, "05" for uint, "1.50" or "1e2" for float, "on" for bool.
+// Values parsed with SetBoolParseFunc or SetNumberLocale are not checked, case of booleans
+// is ignored with SetCaseInsensitive.
+//
+// It has a cost of an extra formatting of every decoded number and boolean, so it is disabled by default.
+func (d *Decoder) SetStrictRoundTrip(enabled bool) {
+	d.strictRoundTrip = enabled
+}
+
+// SetShapeCoercion enables coercion of values that do not match shape of a field, mismatches are
+// reported with SetWarnFunc instead of errors: first of multiple values is used for a scalar field,
+// and a single value for a slice field is decoded as a one-element slice.
+//
+// By default multiple values for a scalar field fail decoding, a single value for a slice field
+// is a valid one-element slice and is decoded without a warning.
+func (d *Decoder) SetShapeCoercion(enabled bool) {
+	d.shapeCoercion = enabled
+}
+
+// SetGroupRepeatedParents enables decoding of struct slices encoded with IndexStyleRepeatedParent,
+// e.g. "items.name=a&items.name=b&items.id=1&items.id=2", element i is decoded from i-th value of every key.
+//
+// Grouping applies only to struct slices without indexed keys, it is disabled by default.
+func (d *Decoder) SetGroupRepeatedParents(enabled bool) {
+	d.groupRepeated = enabled
+}
+
+// SetUseJSONUnmarshaler enables decoding values of json.Unmarshaler types that do not implement
+// encoding.TextUnmarshaler with UnmarshalJSON, it mirrors Encoder.SetUseJSONMarshaler. Empty values are skipped.
+func (d *Decoder) SetUseJSONUnmarshaler(enabled bool) {
+	d.useJSONUnmarshaler = enabled
+}
+
+// SetNumberLocale sets a function to parse locale formatted integer and float values, e.g. "1,234.56",
+// it mirrors Encoder.SetNumberLocale. Integer fields fail to decode fractional values.
+func (d *Decoder) SetNumberLocale(parse func(s string) (float64, error)) {
+	d.numberLocale = parse
+}
+
+// SetSchemaVersionKey sets a key of schema version, it mirrors Encoder.SetSchemaVersion.
+func (d *Decoder) SetSchemaVersionKey(key string) {
+	d.versionKey = key
+}
+
+// SchemaVersion returns the schema version from values, so that decoding can branch on format,
+// it is empty if version is missing or version key is not set.
+func (d *Decoder) SchemaVersion(values url.Values) string {
+	if d.versionKey == "" {
+		return ""
+	}
+
+	return values.Get(d.versionKey)
+}
+
 // RegisterTagNameFunc registers a custom tag name parser function
 // NOTE: This method is not thread-safe it is intended that these all be registered prior to any parsing
 //
@@ -125,6 +455,14 @@ func (d *Decoder) RegisterTagNameFunc(fn TagNameFunc) {
 	d.structCache.tagFn = fn
 }
 
+// SetContextualNameFunc sets a function to rename fields depending on parent struct type,
+// it mirrors Encoder.SetContextualNameFunc.
+//
+// NOTE: This method is not thread-safe it is intended that it is set prior to any parsing.
+func (d *Decoder) SetContextualNameFunc(fn func(parentType reflect.Type, goField string, tag string) string) {
+	d.structCache.contextualNameFn = fn
+}
+
 // RegisterFunc registers a DecodeFunc against a number of types.
 // NOTE: This method is not thread-safe it is intended that these all be registered prior to any parsing
 //
@@ -141,8 +479,141 @@ func (d *Decoder) RegisterFunc(fn DecodeFunc, types ...interface{}) {
 	}
 }
 
+// RegisterBigIntBase registers decoding of big.Int values from text in base from 2 to 62,
+// it mirrors Encoder.RegisterBigIntBase.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (d *Decoder) RegisterBigIntBase(base int) error {
+	if base < 2 || base > big.MaxBase {
+		return fmt.Errorf("form: invalid big.Int base %d, must be from 2 to %d", base, big.MaxBase)
+	}
+
+	d.RegisterFunc(func(s string) (interface{}, error) {
+		var i big.Int
+
+		if _, ok := i.SetString(s, base); !ok {
+			return nil, fmt.Errorf("invalid base %d integer value '%s'", base, s)
+		}
+
+		return i, nil
+	}, big.Int{})
+
+	return nil
+}
+
+// RegisterPattern registers a regular expression to decode a single value into a struct of sample type,
+// named groups of the expression are decoded into fields with matching keys,
+// e.g. `^(?P<Lat>[^,]+),(?P<Lng>[^,]+)$` for a Point struct.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (d *Decoder) RegisterPattern(sample interface{}, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	typ := reflect.TypeOf(sample)
+	names := re.SubexpNames()
+
+	d.RegisterFunc(func(s string) (interface{}, error) {
+		match := re.FindStringSubmatch(s)
+		if match == nil {
+			return nil, fmt.Errorf("value '%s' does not match pattern '%s' of type '%v'", s, pattern, typ)
+		}
+
+		values := make(url.Values, len(names))
+
+		for i, name := range names {
+			if name != "" {
+				values[name] = []string{match[i]}
+			}
+		}
+
+		// groups are decoded without options of the whole input, such as root key or checksum
+		dec := decoder{d: d, values: values}
+		v := reflect.New(typ).Elem()
+
+		if v.Kind() == reflect.Struct {
+			dec.traverseStruct(v, typ, nil)
+		} else {
+			dec.setFieldByType(v, false, nil, 0)
+		}
+
+		if len(dec.errs) > 0 {
+			return nil, dec.errs
+		}
+
+		return v.Interface(), nil
+	}, sample)
+
+	return nil
+}
+
+// SetFallbackFunc sets a function to decode values of types that have no registered or built-in decoder,
+// for example complex numbers, channels or functions.
+//
+// Fallback function is called last in resolution, after custom type functions, encoding.TextUnmarshaler
+// and built-in kinds.
+func (d *Decoder) SetFallbackFunc(fn FallbackDecodeFunc) {
+	d.fallbackFunc = fn
+}
+
+// RegisterInterfaceType registers a concrete type of sample value with a name of discriminator
+// to decode interface values, e.g. url.Values{"shape._type":[]string{"circle"}, "shape.radius":[]string{"1"}}.
+//
+// Interface value is allocated with the type registered for the "_type" key under the namespace
+// of interface field, remaining keys are decoded into the allocated value.
+// Interfaces with methods are only decoded with discriminator.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (d *Decoder) RegisterInterfaceType(name string, sample interface{}) {
+	if d.interfaceTypes == nil {
+		d.interfaceTypes = map[string]reflect.Type{}
+	}
+
+	d.interfaceTypes[name] = reflect.TypeOf(sample)
+}
+
+// RegisterTypesByName registers concrete types of samples to decode interface values with discriminators
+// of Encoder.SetAutoDiscriminator, names of types are used, e.g. "Circle" for Circle{} or &Circle{}.
+//
+// It returns an error and registers nothing if names of different types collide, e.g. for types with
+// the same name from different packages, such types can be registered with RegisterInterfaceType.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (d *Decoder) RegisterTypesByName(samples ...interface{}) error {
+	names := make(map[string]reflect.Type, len(samples))
+
+	for _, s := range samples {
+		t := derefType(reflect.TypeOf(s))
+		name := t.Name()
+
+		prev, ok := names[name]
+		if !ok {
+			if rt, registered := d.interfaceTypes[name]; registered {
+				prev, ok = derefType(rt), true
+			}
+		}
+
+		if ok && prev != t {
+			return fmt.Errorf("form: type name '%s' of '%v' collides with registered '%v'", name, t, prev)
+		}
+
+		names[name] = t
+	}
+
+	for _, s := range samples {
+		d.RegisterInterfaceType(derefType(reflect.TypeOf(s)).Name(), s)
+	}
+
+	return nil
+}
+
 // Decode parses the given values and sets the corresponding struct and/or type values
 //
+// Bracketed keys are resolved by the type of target field, e.g. "field[0]" is an index
+// of a slice or array field, a key "0" of a map[string]T field and a key 0 of a map[int]T field.
+//
 // Decode returns an InvalidDecoderError if interface passed is invalid.
 func (d *Decoder) Decode(v interface{}, values url.Values, collectGoValues ...map[string]interface{}) error {
 	val := reflect.ValueOf(v)
@@ -151,20 +622,37 @@ func (d *Decoder) Decode(v interface{}, values url.Values, collectGoValues ...ma
 		return &InvalidDecoderError{Type: reflect.TypeOf(v)}
 	}
 
+	var goValues map[string]interface{}
+
+	if len(collectGoValues) > 0 {
+		goValues = collectGoValues[0]
+	}
+
+	return d.decode(val.Elem(), values, goValues, nil)
+}
+
+// decode decodes values into addressable val, collecting Go values and populated namespaces if requested.
+func (d *Decoder) decode(val reflect.Value, values url.Values, goValues map[string]interface{}, populated *[]string) error {
 	dec := d.dataPool.Get().(*decoder) //nolint:errcheck
 	dec.values = values
 	dec.dm = dec.dm[0:0]
+	dec.track = populated != nil
 
-	val = val.Elem()
+	if d.sequencePrefix {
+		dec.values = stripSequence(values)
+	}
 
-	if typ := val.Type(); val.Kind() == reflect.Struct && typ != timeType {
-		if len(collectGoValues) > 0 {
-			dec.goValues = collectGoValues[0]
-		}
+	namespace := append(dec.namespace[0:0], d.rootKey...)
 
-		dec.traverseStruct(val, typ, dec.namespace[0:0])
-	} else {
-		dec.setFieldByType(val, false, dec.namespace[0:0], 0)
+	switch typ := val.Type(); {
+	case !dec.checkPathDepth(), !dec.verifyChecksum():
+		// errors of invalid input are already collected
+	case val.Kind() == reflect.Struct && !isTimeType(typ) && !isTextUnmarshaler(val):
+		dec.goValues = goValues
+
+		dec.traverseStruct(val, typ, namespace)
+	default:
+		dec.setFieldByType(val, false, namespace, 0)
 	}
 
 	var err error
@@ -174,9 +662,52 @@ func (d *Decoder) Decode(v interface{}, values url.Values, collectGoValues ...ma
 		dec.errs = nil
 	}
 
+	if populated != nil {
+		*populated = append(*populated, dec.populated...)
+		dec.populated = dec.populated[:0]
+		dec.track = false
+	}
+
 	dec.dmDone = false
+	dec.decrypted = nil
+	dec.bound = nil
+	dec.boundBytes = 0
 
 	d.dataPool.Put(dec)
 
 	return err
 }
+
+// DecodePopulated decodes values like Decode and returns sorted namespaces of fields that were
+// set from input, fields left untouched are not listed, parent structs of set nested fields are listed too.
+//
+// It is useful for partial updates to distinguish fields provided by client from zero values.
+func (d *Decoder) DecodePopulated(v interface{}, values url.Values) ([]string, error) {
+	val := reflect.ValueOf(v)
+
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil, &InvalidDecoderError{Type: reflect.TypeOf(v)}
+	}
+
+	populated := []string{}
+
+	err := d.decode(val.Elem(), values, nil, &populated)
+
+	sort.Strings(populated)
+
+	return populated, err
+}
+
+// DecodeMerged decodes values merged from multiple sources, e.g. query and cookies,
+// later sources take precedence, all values of a key from the winning source replace earlier ones.
+func (d *Decoder) DecodeMerged(v interface{}, sources ...url.Values) error {
+	merged := make(url.Values)
+
+	for _, src := range sources {
+		for k, vals := range src {
+			merged[k] = vals
+		}
+	}
+
+	return d.Decode(v, merged)
+}
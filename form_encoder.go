@@ -2,15 +2,47 @@ package form
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"math/big"
 	"net/url"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"text/template"
 )
 
 // EncodeFunc allows for registering/overriding types to be parsed.
 type EncodeFunc func(x interface{}) (string, error)
 
+// TransformFunc is a function to encode a field value, registered by name with Encoder.RegisterTransform.
+type TransformFunc func(v reflect.Value) (string, error)
+
+// KV is a key-value pair of a form field, it is an alias so that implementers
+// do not need to import the package to satisfy FormFielder.
+type KV = struct {
+	Key   string
+	Value string
+}
+
+// FormFielder is implemented by types that provide their form representation as a list of pairs.
+//
+// Pairs are spliced under the namespace of the value, empty Key refers to the namespace itself.
+type FormFielder interface {
+	FormFields() []KV
+}
+
+// FieldNamer is implemented by structs that resolve names of their fields at encoding time,
+// FormFieldName receives Go name of a field and returns its key overriding the field tag,
+// empty result keeps the name from the field tag.
+type FieldNamer interface {
+	FormFieldName(goFieldName string) string
+}
+
+// KeyFunc returns a key of slice item, see Encoder.RegisterKeyFunc.
+type KeyFunc func(x interface{}) (string, error)
+
 // EncodeErrors is a map of errors encountered during form encoding.
 type EncodeErrors map[string]error
 
@@ -43,12 +75,54 @@ func (e *InvalidEncodeError) Error() string {
 
 // Encoder is the main encode instance.
 type Encoder struct {
-	tagName         string
-	structCache     *structCacheMap
-	customTypeFuncs map[reflect.Type]EncodeFunc
-	dataPool        *sync.Pool
-	mode            Mode
-	embedAnonymous  bool
+	tagName           string
+	structCache       *structCacheMap
+	customTypeFuncs   map[reflect.Type]EncodeFunc
+	keyFuncs          map[reflect.Type]KeyFunc
+	mapKeyFuncs       map[reflect.Type]KeyFunc
+	interfaceTypes    map[reflect.Type]string
+	autoDiscriminator bool
+	dataPool          *sync.Pool
+	mode              Mode
+	embedAnonymous    bool
+	emptyStruct       string
+	nilStruct         string
+	omitDefaults      bool
+	timeLayout        string
+	rootKey           string
+	pluralize         func(string) string
+	numberLocale      func(float64) string
+	reverseFields     bool
+	dedupeStructs     bool
+	fieldPriority     map[string]int
+	debugComments     bool
+	useJSONMarshaler  bool
+	visibility        string
+	visibilities      []string
+	requiredMode      RequiredMode
+	requiredValue     string
+	warnFunc          func(namespace, msg string)
+	versionKey        string
+	version           string
+	idempotencyKey    string
+	idempotencyFunc   func() string
+	sliceLimitMode    SliceLimitMode
+	indexStyle        IndexStyle
+	sharedPtrMode     SharedPointerMode
+	zeroTime          *string
+	nullString        *string
+	nilElement        *string
+	escapeMapKeys     bool
+	mapKeyTransform   func(string) string
+	flattenSingle     bool
+	boolFunc          func(bool) string
+	skipFunc          func(namespace string, v reflect.Value) bool
+	encryptFunc       func(plaintext string) (string, error)
+	jsonBelowDepth    int
+	omitEmptyPolicy   map[reflect.Kind]bool
+	checksumKey       string
+	checksumFunc      func([]byte) string
+	sequenceWidth     int
 }
 
 // NewEncoder creates a new encoder instance with sane defaults.
@@ -58,6 +132,7 @@ func NewEncoder() *Encoder {
 		mode:           ModeImplicit,
 		structCache:    newStructCacheMap(),
 		embedAnonymous: true,
+		visibilities:   []string{"public", "internal", "private"},
 	}
 
 	e.dataPool = &sync.Pool{New: func() interface{} {
@@ -70,6 +145,17 @@ func NewEncoder() *Encoder {
 	return e
 }
 
+// getEncoder returns encoding state from the pool, a single pool is shared by all goroutines
+// as sync.Pool already has per-P caches, see BenchmarkSimpleUserEncodeStructParallelUnshared.
+func (e *Encoder) getEncoder() *encoder {
+	return e.dataPool.Get().(*encoder) //nolint:errcheck
+}
+
+func (e *Encoder) putEncoder(enc *encoder) {
+	enc.reset()
+	e.dataPool.Put(enc)
+}
+
 // SetTagName sets the given tag name to be used by the encoder.
 //
 // Default is "form".
@@ -91,6 +177,314 @@ func (e *Encoder) SetAnonymousMode(mode AnonymousMode) {
 	e.embedAnonymous = mode == AnonymousEmbed
 }
 
+// SetEmptyStructMarker sets a value to emit for nested structs that have all fields at zero values,
+// e.g. url.Values{"field":[]string{"__empty__"}}, instead of exploding such structs into fields.
+//
+// Default is empty, which disables the marker.
+func (e *Encoder) SetEmptyStructMarker(marker string) {
+	e.emptyStruct = marker
+}
+
+// SetNilStructMarker sets a value to emit for nil pointers to nested structs,
+// e.g. url.Values{"field":[]string{"__nil__"}}, to distinguish them from pointers to zero structs
+// that are exploded into fields or emitted with SetEmptyStructMarker.
+//
+// Default is empty, which emits nothing for nil pointers.
+func (e *Encoder) SetNilStructMarker(marker string) {
+	e.nilStruct = marker
+}
+
+// SetSliceLimitMode sets how encoder handles slices and arrays that have more items
+// than allowed with `max` field tag option, e.g. `form:"items,max=100"`.
+//
+// Default is SliceLimitTruncate.
+func (e *Encoder) SetSliceLimitMode(mode SliceLimitMode) {
+	e.sliceLimitMode = mode
+}
+
+// SetIndexStyle sets how keys of slice and array elements are named.
+//
+// Default is IndexStyleAuto.
+func (e *Encoder) SetIndexStyle(style IndexStyle) {
+	e.indexStyle = style
+}
+
+// SetSharedPointerMode sets how encoder handles multiple pointers to the same struct.
+//
+// Default is SharedPointerDuplicate.
+func (e *Encoder) SetSharedPointerMode(mode SharedPointerMode) {
+	e.sharedPtrMode = mode
+}
+
+// SetZeroTimePlaceholder sets a value to emit for zero time.Time, e.g. "" or "0001-01-01".
+//
+// Placeholder is emitted even for fields with `omitempty` tag option, nil *time.Time is still omitted.
+func (e *Encoder) SetZeroTimePlaceholder(placeholder string) {
+	e.zeroTime = &placeholder
+}
+
+// SetStringThreeState enables three-state encoding of optional text: empty string emits nullLit,
+// e.g. url.Values{"name":[]string{"null"}}, nil *string is absent and non-empty string is emitted as is.
+//
+// Decoder should have matching SetStringThreeState option, non-empty string equal to nullLit
+// is decoded as empty string.
+//
+// Disabled by default, empty string is emitted as empty value.
+func (e *Encoder) SetStringThreeState(enabled bool, nullLit string) {
+	e.nullString = nil
+
+	if enabled {
+		e.nullString = &nullLit
+	}
+}
+
+// SetNilElementPlaceholder sets a value to emit for nil pointer elements of slices and arrays,
+// e.g. "items[1]=", to keep indices of following elements aligned, instead of skipping nil elements.
+func (e *Encoder) SetNilElementPlaceholder(placeholder string) {
+	e.nilElement = &placeholder
+}
+
+// SetFallbackToJSONTag enables using `json` field tag (including "-" and "omitempty")
+// for fields that have no encoder tag.
+//
+// NOTE: This method is not thread-safe it is intended to be called prior to any parsing.
+func (e *Encoder) SetFallbackToJSONTag(enabled bool) {
+	e.structCache.jsonFallback = enabled
+}
+
+// SetEscapeMapKeys enables percent-encoding of '%', '.', '[' and ']' in map keys,
+// so that keys with such characters are unambiguous, e.g. "field[a%2Eb]" for key "a.b".
+//
+// Decoder should have matching SetEscapeMapKeys option enabled to decode such keys.
+func (e *Encoder) SetEscapeMapKeys(enabled bool) {
+	e.escapeMapKeys = enabled
+}
+
+// SetFlattenSingleMap enables encoding of maps with exactly one entry as a scalar value
+// under the field name, e.g. "field=value" instead of "field[key]=value".
+//
+// NOTE: map key is dropped, so such values can not be decoded back into maps.
+func (e *Encoder) SetFlattenSingleMap(enabled bool) {
+	e.flattenSingle = enabled
+}
+
+// SetBoolFunc sets a function to format boolean values at any depth, including map keys,
+// e.g. to render localized "yes"/"no".
+//
+// Default is strconv.FormatBool.
+func (e *Encoder) SetBoolFunc(fn func(bool) string) {
+	e.boolFunc = fn
+}
+
+// SetSkipFunc sets a function that is called for every encoded leaf value with its namespace,
+// the value is skipped if function returns true.
+//
+// It is applied in addition to field tag options, so the value is skipped if any of them says so.
+func (e *Encoder) SetSkipFunc(fn func(namespace string, v reflect.Value) bool) {
+	e.skipFunc = fn
+}
+
+// SetEncryptFunc sets a function to encrypt values of fields with `encrypt` tag option, e.g. `form:"ssn,encrypt"`,
+// every value of such field is encrypted, including values of nested fields and elements.
+//
+// Decoder should have matching SetDecryptFunc option. Fields with `encrypt` tag option fail encoding
+// if function is not set, so that plaintext is never emitted.
+func (e *Encoder) SetEncryptFunc(fn func(plaintext string) (string, error)) {
+	e.encryptFunc = fn
+}
+
+// SetJSONBelowDepth enables encoding of structs nested deeper than depth levels as JSON
+// under a single key to limit the number of keys for deeply nested values,
+// e.g. with depth 1 struct field "a" is exploded and its struct field "b" is encoded as `a.b={"c":1}`.
+//
+// Decoder should have matching SetJSONBelowDepth option to decode such keys.
+//
+// Default is 0, which disables JSON encoding.
+func (e *Encoder) SetJSONBelowDepth(depth int) {
+	e.jsonBelowDepth = depth
+}
+
+// SetOmitEmptyPolicy sets whether empty values of struct fields are omitted per kind of field,
+// e.g. map[reflect.Kind]bool{reflect.Int: true, reflect.String: false} omits zero ints and keeps empty strings.
+//
+// Policy takes precedence over omitempty field tag option for kinds present in the map,
+// fields of other kinds follow the tag. Pointer fields have reflect.Ptr kind.
+func (e *Encoder) SetOmitEmptyPolicy(policy map[reflect.Kind]bool) {
+	e.omitEmptyPolicy = policy
+}
+
+// SetChecksumKey enables adding a checksum of encoded values under the key,
+// checksum is computed with fn over canonical form of other values, that is url.Values.Encode
+// with keys sorted.
+//
+// Decoder should have matching SetVerifyChecksum option to verify such values.
+func (e *Encoder) SetChecksumKey(key string, fn func([]byte) string) {
+	e.checksumKey = key
+	e.checksumFunc = fn
+}
+
+// SetSequencePrefix enables prefixing of keys with a sequence number padded with zeros to width
+// in the order of emission, e.g. "001:field", to preserve the order of values in url.Values.
+// Repeated values of a key share the same number, sequence is applied after checksum.
+//
+// Decoder should have matching SetSequencePrefix option to strip the prefix.
+func (e *Encoder) SetSequencePrefix(enabled bool, width int) {
+	e.sequenceWidth = 0
+
+	if enabled {
+		e.sequenceWidth = width
+
+		if width < 1 {
+			e.sequenceWidth = 1
+		}
+	}
+}
+
+// SetOmitDefaults enables omitting of scalar fields that are equal to value of `default` field tag option,
+// e.g. `form:"limit,default=10"`, as such values are expected to be filled by the receiver.
+func (e *Encoder) SetOmitDefaults(enabled bool) {
+	e.omitDefaults = enabled
+}
+
+// SetTimeLayout sets a layout to format time.Time values at any depth, including slice elements.
+//
+// Default is empty, time.Time fields are encoded with MarshalText and other values with time.RFC3339.
+// Decoder should have matching SetTimeLayouts option to decode such values.
+func (e *Encoder) SetTimeLayout(layout string) {
+	e.timeLayout = layout
+}
+
+// SetRootKey sets a key to wrap all encoded values, e.g. "data.name" instead of "name" for root key "data".
+//
+// Decoder should have matching SetRootKey option to decode such values.
+func (e *Encoder) SetRootKey(key string) {
+	e.rootKey = key
+}
+
+// SetPluralizeSliceKeys enables pluralized keys of slice and array fields, e.g. "tags" for a field tagged "tag".
+//
+// Default rules append "s", "es" after "s", "x", "z", "ch", "sh", and replace trailing "y" after a consonant with "ies".
+// Decoder should have matching SetPluralizeSliceKeys option to decode such values.
+func (e *Encoder) SetPluralizeSliceKeys(enabled bool) {
+	e.pluralize = nil
+
+	if enabled {
+		e.pluralize = pluralize
+	}
+}
+
+// SetPluralizeFunc sets a custom function to pluralize keys of slice and array fields, nil disables pluralization.
+func (e *Encoder) SetPluralizeFunc(fn func(name string) string) {
+	e.pluralize = fn
+}
+
+// SetNumberLocale sets a function to format integer and float values for human-facing forms,
+// e.g. "1,234.56" with thousands separators, integers are converted to float64 and may lose precision beyond 2^53.
+//
+// Decoder should have matching SetNumberLocale parser to decode such values.
+func (e *Encoder) SetNumberLocale(format func(f float64) string) {
+	e.numberLocale = format
+}
+
+// SetReverseFieldOrder enables encoding struct fields in reverse declaration order,
+// it affects order of columns of EncodeWithColumns and of pairs of streaming output, but not encoded values.
+func (e *Encoder) SetReverseFieldOrder(enabled bool) {
+	e.reverseFields = enabled
+}
+
+// SetFieldPriority sets priorities to order columns of EncodeWithColumns and pairs of streaming output,
+// lower priority first, ties keep declaration order. A column takes priority of its full key, e.g. "user.name",
+// or of its top level field, e.g. "user", columns without priority have priority 0.
+func (e *Encoder) SetFieldPriority(priority map[string]int) {
+	e.fieldPriority = priority
+}
+
+// SetDedupeStructSlices enables encoding equal struct elements of slices and arrays only once,
+// at the index of compacted unique elements in order of first occurrence.
+//
+// Elements are compared with reflect.DeepEqual to each previous unique element, which is O(n^2)
+// for a slice of n elements, so it is disabled by default. Pointer elements are compared by pointed values.
+func (e *Encoder) SetDedupeStructSlices(enabled bool) {
+	e.dedupeStructs = enabled
+}
+
+// SetVisibility enables encoding only fields at or below visibility level in the ladder of
+// SetVisibilityLevels, set with `visibility=` tag option, e.g. `form:"ssn,visibility=internal"`.
+// Fields without visibility option are always encoded, fields of unknown level are not encoded.
+//
+// Default is empty level, which encodes all fields.
+func (e *Encoder) SetVisibility(level string) {
+	e.visibility = level
+}
+
+// SetVisibilityLevels sets ordered visibility ladder from the most to the least visible level,
+// default is "public", "internal", "private".
+func (e *Encoder) SetVisibilityLevels(levels ...string) {
+	e.visibilities = levels
+}
+
+// isVisible reports whether field of visibility level is encoded with requested visibility.
+func (e *Encoder) isVisible(level string) bool {
+	requested, field := -1, -1
+
+	for i, l := range e.visibilities {
+		if l == e.visibility {
+			requested = i
+		}
+
+		if l == level {
+			field = i
+		}
+	}
+
+	return requested != -1 && field != -1 && field <= requested
+}
+
+// SetRequiredMode sets how empty fields with `required` tag option are encoded.
+//
+// Default is RequiredError.
+func (e *Encoder) SetRequiredMode(mode RequiredMode) {
+	e.requiredMode = mode
+}
+
+// SetRequiredPlaceholder sets a value to encode for empty required fields in RequiredPlaceholder mode.
+//
+// Default is empty.
+func (e *Encoder) SetRequiredPlaceholder(placeholder string) {
+	e.requiredValue = placeholder
+}
+
+// SetWarnFunc sets a function to receive warnings about recoverable issues of encoded values.
+func (e *Encoder) SetWarnFunc(fn func(namespace, msg string)) {
+	e.warnFunc = fn
+}
+
+// SetSchemaVersion enables adding a version of encoded schema under the key to every result,
+// the key overrides a struct field with the same key, if any.
+//
+// Decoder can read the version with SchemaVersion after SetSchemaVersionKey.
+func (e *Encoder) SetSchemaVersion(key string, version string) {
+	e.versionKey = key
+	e.version = version
+}
+
+// SetIdempotencyKeyFunc enables adding a fresh idempotency key generated by fn under the key to every result,
+// e.g. to make retries of a form request safe. The key is set exactly once and overrides a struct field
+// with the same key, if any. Nil fn disables the key.
+func (e *Encoder) SetIdempotencyKeyFunc(key string, fn func() string) {
+	e.idempotencyKey = key
+	e.idempotencyFunc = fn
+}
+
+// SetMapKeyTransform sets a function to transform string map keys before encoding,
+// e.g. to convert camelCase keys to snake_case. Field names are not affected.
+//
+// Decoder can restore original keys with inverse Decoder.SetMapKeyTransform,
+// only if the transform is invertible (e.g. "userID" and "userId" both become "user_id").
+func (e *Encoder) SetMapKeyTransform(fn func(key string) string) {
+	e.mapKeyTransform = fn
+}
+
 // RegisterTagNameFunc registers a custom tag name parser function
 // NOTE: This method is not thread-safe it is intended that these all be registered prior to any parsing
 //
@@ -101,6 +495,17 @@ func (e *Encoder) RegisterTagNameFunc(fn TagNameFunc) {
 	e.structCache.tagFn = fn
 }
 
+// SetContextualNameFunc sets a function to rename fields depending on parent struct type, e.g. to name
+// an embedded or nested field of the same type "home" in one struct and "office" in another.
+// The function receives field name resolved from tag, empty result keeps it.
+//
+// The return value is cached per parent type and field, so it must be consistent.
+//
+// NOTE: This method is not thread-safe it is intended that it is set prior to any parsing.
+func (e *Encoder) SetContextualNameFunc(fn func(parentType reflect.Type, goField string, tag string) string) {
+	e.structCache.contextualNameFn = fn
+}
+
 // RegisterFunc registers a EncodeFunc against a number of types.
 //
 // NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
@@ -114,35 +519,222 @@ func (e *Encoder) RegisterFunc(fn EncodeFunc, types ...interface{}) {
 	}
 }
 
+// RegisterBigIntBase registers encoding of big.Int values as text in base from 2 to 62,
+// e.g. base 62 for short IDs, negative values have "-" prefix.
+//
+// Decoder should have matching RegisterBigIntBase to decode such values.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (e *Encoder) RegisterBigIntBase(base int) error {
+	if base < 2 || base > big.MaxBase {
+		return fmt.Errorf("form: invalid big.Int base %d, must be from 2 to %d", base, big.MaxBase)
+	}
+
+	e.RegisterFunc(func(x interface{}) (string, error) {
+		i := x.(big.Int) //nolint:errcheck // Registered for big.Int only.
+
+		return i.Text(base), nil
+	}, big.Int{})
+
+	return nil
+}
+
+// RegisterTemplate registers a text/template to encode values of sample type into a single value,
+// e.g. "{{.Lat}},{{.Lng}}" for a Point struct.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (e *Encoder) RegisterTemplate(sample interface{}, tmpl string) error {
+	t, err := template.New(reflect.TypeOf(sample).String()).Parse(tmpl)
+	if err != nil {
+		return err
+	}
+
+	e.RegisterFunc(func(x interface{}) (string, error) {
+		var buf strings.Builder
+
+		if err := t.Execute(&buf, x); err != nil {
+			return "", err
+		}
+
+		return buf.String(), nil
+	}, sample)
+
+	return nil
+}
+
+// RegisterStringerLayout registers a function to format values of sample type that is not a plain
+// time.Time, e.g. civil.Date, into a single value, it is a shorthand for RegisterFunc that can not fail.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (e *Encoder) RegisterStringerLayout(sample interface{}, layoutFn func(x interface{}) string) {
+	e.RegisterFunc(func(x interface{}) (string, error) {
+		return layoutFn(x), nil
+	}, sample)
+}
+
+// RegisteredTypes returns types with registered EncodeFunc sorted by name.
+func (e *Encoder) RegisteredTypes() []reflect.Type {
+	types := make([]reflect.Type, 0, len(e.customTypeFuncs))
+
+	for t := range e.customTypeFuncs {
+		types = append(types, t)
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].String() < types[j].String()
+	})
+
+	return types
+}
+
+// RegisterKeyFunc registers a KeyFunc against a number of types to encode slices and arrays of these
+// types as keyed items, e.g. "items[key].field" instead of "items[0].field".
+//
+// Key can be derived from any logic, for example by joining multiple fields: item.Region + ":" + item.ID.
+// Keyed slice can be decoded into a map (e.g. map[string]Item), but not back into a slice,
+// so such encoding is one-way for slices.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (e *Encoder) RegisterKeyFunc(fn KeyFunc, types ...interface{}) {
+	if e.keyFuncs == nil {
+		e.keyFuncs = map[reflect.Type]KeyFunc{}
+	}
+
+	for _, t := range types {
+		e.keyFuncs[derefType(reflect.TypeOf(t))] = fn
+	}
+}
+
+// RegisterMapKeyFunc registers a KeyFunc to serialize map keys of sample type,
+// e.g. "points[1:2]" for map[Point]int with key func that joins coordinates.
+//
+// Maps with non-scalar keys of types without registered key func fail to encode with an error.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (e *Encoder) RegisterMapKeyFunc(sample interface{}, fn KeyFunc) {
+	if e.mapKeyFuncs == nil {
+		e.mapKeyFuncs = map[reflect.Type]KeyFunc{}
+	}
+
+	e.mapKeyFuncs[reflect.TypeOf(sample)] = fn
+}
+
+// RegisterTransform registers a TransformFunc by name for fields with `transform=` tag option,
+// e.g. `form:"card,transform=mask"`, the function receives field value with pointers dereferenced.
+//
+// Fields with names of transforms that are not registered fail to encode with an error.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (e *Encoder) RegisterTransform(name string, fn TransformFunc) {
+	if e.structCache.transforms == nil {
+		e.structCache.transforms = map[string]TransformFunc{}
+	}
+
+	e.structCache.transforms[name] = fn
+}
+
+// RegisterInterfaceType registers a name of discriminator for concrete type of sample value,
+// when a value of this type is held by an interface, discriminator is added under "_type" key,
+// e.g. url.Values{"shape._type":[]string{"circle"}, "shape.radius":[]string{"1"}}.
+//
+// NOTE: this method is not thread-safe it is intended that these all be registered prior to any parsing.
+func (e *Encoder) RegisterInterfaceType(name string, sample interface{}) {
+	if e.interfaceTypes == nil {
+		e.interfaceTypes = map[reflect.Type]string{}
+	}
+
+	e.interfaceTypes[reflect.TypeOf(sample)] = name
+}
+
+// SetAutoDiscriminator enables adding discriminator under "_type" key for struct values held by interfaces
+// without registered name, name of the struct type is used, e.g. "Circle" for Circle or *Circle.
+//
+// Decoder can use the same names with RegisterTypesByName.
+func (e *Encoder) SetAutoDiscriminator(enabled bool) {
+	e.autoDiscriminator = enabled
+}
+
+// SetDebugComments enables adding a no-op "_debug_path" key with encoded type, e.g. "_debug_path=form.User",
+// before other keys to make logged values easier to read. Decoder ignores it as an unknown key.
+func (e *Encoder) SetDebugComments(enabled bool) {
+	e.debugComments = enabled
+}
+
+// SetUseJSONMarshaler enables encoding values of json.Marshaler types that do not implement
+// encoding.TextMarshaler or FormFielder as a single value with MarshalJSON result.
+//
+// Decoder should have matching SetUseJSONUnmarshaler option to decode such values.
+func (e *Encoder) SetUseJSONMarshaler(enabled bool) {
+	e.useJSONMarshaler = enabled
+}
+
 // Encode encodes the given values and sets the corresponding struct values.
 func (e *Encoder) Encode(v interface{}, collectGoValues ...map[string]interface{}) (values url.Values, err error) {
-	val, kind := ExtractType(reflect.ValueOf(v))
+	enc := e.getEncoder()
 
-	if kind == reflect.Ptr || kind == reflect.Interface || kind == reflect.Invalid {
-		return nil, &InvalidEncodeError{Type: reflect.TypeOf(v)}
+	if len(collectGoValues) > 0 {
+		enc.goValues = collectGoValues[0]
 	}
 
-	enc := e.dataPool.Get().(*encoder) //nolint:errcheck
-	enc.values = make(url.Values)
+	err = enc.encode(v)
+	values = enc.values
 
-	if kind == reflect.Struct && val.Type() != timeType {
-		if len(collectGoValues) > 0 {
-			enc.goValues = collectGoValues[0]
-		}
+	e.putEncoder(enc)
 
-		enc.traverseStruct(val, enc.namespace[0:0], -1)
-	} else {
-		enc.setFieldByType(val, enc.namespace[0:0], -1, cachedField{})
+	return
+}
+
+// EncodeSparse encodes the given values like all struct fields have omitempty option,
+// so that only non-empty values are present in result.
+func (e *Encoder) EncodeSparse(v interface{}) (values url.Values, err error) {
+	enc := e.getEncoder()
+	enc.sparse = true
+
+	err = enc.encode(v)
+	values = enc.values
+
+	e.putEncoder(enc)
+
+	return
+}
+
+// EncodeMasked encodes only struct fields enabled in mask, mask is a struct with fields of the same
+// names as in v, a true bool field enables the field entirely and a struct field enables
+// fields of nested struct (or structs in slices and maps) in the same manner.
+func (e *Encoder) EncodeMasked(v interface{}, mask interface{}) (values url.Values, err error) {
+	m, kind := ExtractType(reflect.ValueOf(mask))
+	if kind != reflect.Struct {
+		return nil, fmt.Errorf("form: mask must be a struct, %v given", reflect.TypeOf(mask))
 	}
 
-	if len(enc.errs) > 0 {
-		err = enc.errs
-		enc.errs = nil
+	enc := e.getEncoder()
+	enc.mask = m
+
+	err = enc.encode(v)
+	values = enc.values
+
+	e.putEncoder(enc)
+
+	return
+}
+
+// EncodeForGroups encodes fields of requested groups, set with `group=` tag option, e.g. `form:"name,required,group=create|update"`,
+// fields without groups are always encoded.
+//
+// Empty required fields of requested groups fail encoding with errors keyed by field namespace
+// and scoped to the group, e.g. "required field is empty in group 'create'", all of them are aggregated in EncodeErrors.
+func (e *Encoder) EncodeForGroups(v interface{}, groups ...string) (values url.Values, err error) {
+	enc := e.getEncoder()
+	enc.groups = groups
+
+	if enc.groups == nil {
+		enc.groups = []string{}
 	}
 
+	err = enc.encode(v)
 	values = enc.values
 
-	e.dataPool.Put(enc)
+	e.putEncoder(enc)
 
 	return
 }
@@ -150,31 +742,61 @@ func (e *Encoder) Encode(v interface{}, collectGoValues ...map[string]interface{
 // EncodeWithColumns encodes the given values and sets the corresponding struct values,
 // additionally returning slice of column names in original order.
 func (e *Encoder) EncodeWithColumns(v interface{}) (values url.Values, columns []string, err error) {
-	val, kind := ExtractType(reflect.ValueOf(v))
+	enc := e.getEncoder()
+	enc.columns = make([]string, 0)
 
-	if kind == reflect.Ptr || kind == reflect.Interface || kind == reflect.Invalid {
-		return nil, nil, &InvalidEncodeError{Type: reflect.TypeOf(v)}
+	err = enc.encode(v)
+	values = enc.values
+	columns = enc.columns
+
+	if values == nil {
+		columns = nil
 	}
 
-	enc := e.dataPool.Get().(*encoder) //nolint:errcheck
-	enc.values = make(url.Values)
-	enc.columns = make([]string, 0)
+	e.putEncoder(enc)
 
-	if kind == reflect.Struct && val.Type() != timeType {
-		enc.traverseStruct(val, enc.namespace[0:0], -1)
-	} else {
-		enc.setFieldByType(val, enc.namespace[0:0], -1, cachedField{})
+	return
+}
+
+// EncodeToWriter encodes the given value and writes it to w in URL-encoded form, e.g. "a=1&b=2".
+//
+// Pairs are written in the order of encoding, so struct fields follow declaration order.
+//
+// Values of fields with `noescape` tag option (e.g. `form:"token,noescape"`) are written as is,
+// without URL escaping. Such values must be trusted, as special characters like '&', '=' or '#'
+// in them would inject extra pairs or break the output.
+func (e *Encoder) EncodeToWriter(w io.Writer, v interface{}) error {
+	enc := e.getEncoder()
+	enc.columns = enc.reusedColumns()
+
+	err := enc.encode(v)
+	if err == nil {
+		err = enc.writeTo(w)
 	}
 
-	if len(enc.errs) > 0 {
-		err = enc.errs
-		enc.errs = nil
+	// columns are not exposed, so they are kept for reuse
+	enc.colsBuf = enc.columns[:0]
+
+	e.putEncoder(enc)
+
+	return err
+}
+
+// EncodeToStringBuilder encodes the given values in URL-encoded form directly into the builder,
+// following the same order and escaping as EncodeToWriter.
+func (e *Encoder) EncodeToStringBuilder(b *strings.Builder, v interface{}) error {
+	enc := e.getEncoder()
+	enc.columns = enc.reusedColumns()
+
+	err := enc.encode(v)
+	if err == nil {
+		b.Grow(enc.encodedLen())
+		err = enc.writeStrings(b)
 	}
 
-	values = enc.values
-	columns = enc.columns
+	enc.colsBuf = enc.columns[:0]
 
-	e.dataPool.Put(enc)
+	e.putEncoder(enc)
 
-	return
+	return err
 }
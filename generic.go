@@ -0,0 +1,66 @@
+package form
+
+import "net/url"
+
+var (
+	defaultEncoder = NewEncoder()
+	defaultDecoder = NewDecoder()
+)
+
+// Marshal encodes the given value into url.Values using encoder with default settings.
+func Marshal[T any](v T) (url.Values, error) {
+	return defaultEncoder.Encode(v)
+}
+
+// Unmarshal decodes the given values into a new value of T using decoder with default settings.
+func Unmarshal[T any](values url.Values) (T, error) {
+	var v T
+
+	err := defaultDecoder.Decode(&v, values)
+
+	return v, err
+}
+
+// PresenceSetter is implemented by types that track presence of their key in decoded values,
+// decoder calls SetPresence(true) when the key of field is present, even with an empty value.
+type PresenceSetter interface {
+	SetPresence(present bool)
+}
+
+// Optional is a field wrapper with three-state semantics for partial updates:
+// absent key leaves Set false, present empty value sets Set and Null, present value sets Set and Value.
+//
+// Encoder skips unset Optional, encodes null Optional as an empty value and Value otherwise.
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// SetPresence implements PresenceSetter.
+func (o *Optional[T]) SetPresence(present bool) {
+	o.Set = present
+}
+
+func (o *Optional[T]) optionalValue() interface{} {
+	return &o.Value
+}
+
+func (o *Optional[T]) setNull() {
+	o.Null = true
+}
+
+func (o Optional[T]) optionalState() (set bool, null bool, value interface{}) {
+	return o.Set, o.Null, o.Value
+}
+
+// optionalDecoder is implemented by Optional to decode its value.
+type optionalDecoder interface {
+	optionalValue() interface{}
+	setNull()
+}
+
+// optionalEncoder is implemented by Optional to encode its value.
+type optionalEncoder interface {
+	optionalState() (set bool, null bool, value interface{})
+}
@@ -0,0 +1,128 @@
+package form_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/form/v5"
+)
+
+func TestMarshal(t *testing.T) {
+	type S struct {
+		Name string   `form:"name"`
+		Tags []string `form:"tags"`
+		Age  *int     `form:"age,omitempty"`
+	}
+
+	values, err := form.Marshal(S{Name: "foo", Tags: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"name": {"foo"}, "tags": {"a", "b"}}, values)
+
+	age := 3
+
+	values, err = form.Marshal(&S{Name: "bar", Age: &age})
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"name": {"bar"}, "age": {"3"}}, values)
+
+	s, err := form.Unmarshal[S](values)
+	require.NoError(t, err)
+	assert.Equal(t, S{Name: "bar", Age: &age}, s)
+
+	ps, err := form.Unmarshal[*S](url.Values{"name": {"baz"}})
+	require.NoError(t, err)
+	assert.Equal(t, &S{Name: "baz"}, ps)
+
+	_, err = form.Unmarshal[S](url.Values{"age": {"abc"}})
+	assert.Error(t, err)
+}
+
+func TestOptional(t *testing.T) {
+	type Update struct {
+		Age   form.Optional[int]      `form:"age"`
+		Name  form.Optional[string]   `form:"name"`
+		Score *form.Optional[float64] `form:"score"`
+		Note  string                  `form:"note"`
+	}
+
+	var u Update
+
+	err := form.NewDecoder().Decode(&u, url.Values{"age": {"3"}, "name": {""}})
+	require.NoError(t, err)
+	assert.Equal(t, form.Optional[int]{Set: true, Value: 3}, u.Age)
+	assert.Equal(t, form.Optional[string]{Set: true, Null: true}, u.Name)
+	assert.Nil(t, u.Score)
+
+	u = Update{}
+
+	err = form.NewDecoder().Decode(&u, url.Values{"score": {"1.5"}, "note": {"n"}})
+	require.NoError(t, err)
+	assert.False(t, u.Age.Set)
+	assert.False(t, u.Name.Set)
+	assert.Equal(t, &form.Optional[float64]{Set: true, Value: 1.5}, u.Score)
+
+	err = form.NewDecoder().Decode(&u, url.Values{"age": {"abc"}})
+	assert.Error(t, err)
+
+	values, err := form.Marshal(Update{
+		Age:  form.Optional[int]{Set: true, Value: 3},
+		Name: form.Optional[string]{Set: true, Null: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"age": {"3"}, "name": {""}, "note": {""}}, values)
+}
+
+func TestOptional_struct(t *testing.T) {
+	type Address struct {
+		City string `form:"city"`
+		Zip  int    `form:"zip"`
+	}
+
+	type Update struct {
+		Address form.Optional[Address] `form:"address"`
+		Other   form.Optional[Address] `form:"other"`
+	}
+
+	u := Update{Address: form.Optional[Address]{Set: true, Value: Address{City: "Oslo", Zip: 150}}}
+
+	values, err := form.Marshal(u)
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"address.city": {"Oslo"}, "address.zip": {"150"}}, values)
+
+	decoded, err := form.Unmarshal[Update](values)
+	require.NoError(t, err)
+	assert.Equal(t, u, decoded)
+
+	decoded, err = form.Unmarshal[Update](url.Values{"other": {""}, "address[city]": {"Bergen"}})
+	require.NoError(t, err)
+	assert.Equal(t, form.Optional[Address]{Set: true, Null: true}, decoded.Other)
+	assert.True(t, decoded.Address.Set)
+}
+
+type presence struct {
+	Present bool
+	Value   string
+}
+
+func (p *presence) SetPresence(present bool) {
+	p.Present = present
+}
+
+func (p *presence) UnmarshalText(text []byte) error {
+	p.Value = string(text)
+
+	return nil
+}
+
+func TestPresenceSetter(t *testing.T) {
+	type Test struct {
+		A presence `form:"a"`
+		B presence `form:"b"`
+	}
+
+	s, err := form.Unmarshal[Test](url.Values{"a": {"x"}})
+	require.NoError(t, err)
+	assert.Equal(t, presence{Present: true, Value: "x"}, s.A)
+	assert.Equal(t, presence{}, s.B)
+}
@@ -1,8 +1,14 @@
 module github.com/swaggest/form/v5
 
-go 1.13
+go 1.18
 
 require (
 	github.com/bool64/dev v0.2.25
 	github.com/stretchr/testify v1.4.0
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+)
@@ -0,0 +1,60 @@
+package form
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxMemory is the maximum size of multipart form kept in memory, same as in net/http.
+const defaultMaxMemory = 32 << 20
+
+// DecodeRequest parses the given request and decodes its values into v.
+//
+// Values are taken from URL query for requests without body (GET, HEAD, DELETE, etc.) and from
+// request body form for POST, PUT and PATCH requests, multipart/form-data body is also supported.
+func (d *Decoder) DecodeRequest(v interface{}, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")) //nolint:errcheck
+
+		if ct == "multipart/form-data" {
+			if err := r.ParseMultipartForm(defaultMaxMemory); err != nil {
+				return err
+			}
+		} else if err := r.ParseForm(); err != nil {
+			return err
+		}
+
+		return d.Decode(v, r.PostForm)
+	default:
+		return d.Decode(v, r.URL.Query())
+	}
+}
+
+// DecodeAny decodes request into v, routing by Content-Type: JSON body ("application/json" or a "+json" type)
+// is decoded with encoding/json and so follows `json` field tags, other requests are decoded with DecodeRequest.
+//
+// Same struct can serve both paths when its fields have both `form` and `json` tags, or when
+// only `json` tags are used with SetFallbackToJSONTag enabled. Empty JSON body leaves v unchanged.
+func (d *Decoder) DecodeAny(v interface{}, r *http.Request) error {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")) //nolint:errcheck
+
+	if ct != "application/json" && !strings.HasSuffix(ct, "+json") {
+		return d.DecodeRequest(v, r)
+	}
+
+	if r.Body == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to decode JSON body: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package form_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/form/v5"
+)
+
+func TestDecoder_DecodeRequest(t *testing.T) {
+	type S struct {
+		Name string   `form:"name"`
+		Tags []string `form:"tags"`
+		Age  int      `form:"age"`
+	}
+
+	dec := form.NewDecoder()
+
+	t.Run("get", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/?name=foo&tags=a&tags=b&age=3", nil)
+
+		var s S
+
+		require.NoError(t, dec.DecodeRequest(&s, r))
+		assert.Equal(t, S{Name: "foo", Tags: []string{"a", "b"}, Age: 3}, s)
+	})
+
+	t.Run("post", func(t *testing.T) {
+		body := url.Values{"name": {"bar"}, "tags": {"c"}}.Encode()
+		r := httptest.NewRequest(http.MethodPost, "/?age=5", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var s S
+
+		require.NoError(t, dec.DecodeRequest(&s, r))
+		assert.Equal(t, S{Name: "bar", Tags: []string{"c"}}, s)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		w := multipart.NewWriter(buf)
+		require.NoError(t, w.WriteField("name", "baz"))
+		require.NoError(t, w.WriteField("age", "7"))
+		require.NoError(t, w.Close())
+
+		r := httptest.NewRequest(http.MethodPut, "/", buf)
+		r.Header.Set("Content-Type", w.FormDataContentType())
+
+		var s S
+
+		require.NoError(t, dec.DecodeRequest(&s, r))
+		assert.Equal(t, S{Name: "baz", Age: 7}, s)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=%zz"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var s S
+
+		assert.Error(t, dec.DecodeRequest(&s, r))
+	})
+}
+
+func TestDecoder_DecodeAny(t *testing.T) {
+	type S struct {
+		Name string   `form:"name" json:"name"`
+		Tags []string `form:"tags" json:"tags"`
+		Age  int      `json:"age"`
+	}
+
+	dec := form.NewDecoder()
+	dec.SetFallbackToJSONTag(true)
+
+	t.Run("form", func(t *testing.T) {
+		body := url.Values{"name": {"foo"}, "tags": {"a", "b"}, "age": {"3"}}.Encode()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var s S
+
+		require.NoError(t, dec.DecodeAny(&s, r))
+		assert.Equal(t, S{Name: "foo", Tags: []string{"a", "b"}, Age: 3}, s)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"foo","tags":["a","b"],"age":3}`))
+		r.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+		var s S
+
+		require.NoError(t, dec.DecodeAny(&s, r))
+		assert.Equal(t, S{Name: "foo", Tags: []string{"a", "b"}, Age: 3}, s)
+	})
+
+	t.Run("json suffix", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name":"bar"}`))
+		r.Header.Set("Content-Type", "application/merge-patch+json")
+
+		var s S
+
+		require.NoError(t, dec.DecodeAny(&s, r))
+		assert.Equal(t, S{Name: "bar"}, s)
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+		r.Header.Set("Content-Type", "application/json")
+
+		s := S{Name: "keep"}
+
+		require.NoError(t, dec.DecodeAny(&s, r))
+		assert.Equal(t, S{Name: "keep"}, s)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"age":"x"}`))
+		r.Header.Set("Content-Type", "application/json")
+
+		var s S
+
+		assert.Error(t, dec.DecodeAny(&s, r))
+	})
+
+	t.Run("query", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/?name=baz&age=4", nil)
+
+		var s S
+
+		require.NoError(t, dec.DecodeAny(&s, r))
+		assert.Equal(t, S{Name: "baz", Age: 4}, s)
+	})
+}
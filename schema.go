@@ -0,0 +1,103 @@
+package form
+
+import (
+	"encoding"
+	"reflect"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	formFielderType   = reflect.TypeOf((*FormFielder)(nil)).Elem()
+)
+
+// FieldSchema describes a field of encoded value.
+type FieldSchema struct {
+	// Namespace is a key of the field in encoded url.Values, e.g. "address.city".
+	Namespace string
+
+	// Type is a Go type of the field.
+	Type reflect.Type
+
+	// Required is true if field has `required` tag option.
+	Required bool
+
+	// Options contains tag options of the field, e.g. "omitempty".
+	Options []string
+}
+
+// Schema returns descriptors of fields of a struct value, nested structs are expanded.
+//
+// Slices, arrays and maps are described as a single field. Schema is based on
+// cached struct metadata of encoder and is safe to call concurrently.
+func (e *Encoder) Schema(v interface{}) []FieldSchema {
+	typ := reflect.TypeOf(v)
+	if typ == nil {
+		return nil
+	}
+
+	typ = derefType(typ)
+	if !e.isTraversable(typ) {
+		return nil
+	}
+
+	return e.schema(nil, typ, "", map[reflect.Type]bool{})
+}
+
+func (e *Encoder) schema(fields []FieldSchema, typ reflect.Type, namespace string, visited map[reflect.Type]bool) []FieldSchema {
+	if visited[typ] {
+		return fields
+	}
+
+	visited[typ] = true
+	defer delete(visited, typ)
+
+	s, ok := e.structCache.Get(typ)
+	if !ok {
+		s = e.structCache.parseStruct(e.mode, typ, e.tagName)
+	}
+
+	for _, f := range s.fields {
+		ft := typ.Field(f.idx).Type
+
+		if f.isAnonymous && e.embedAnonymous {
+			if f.hasExportedScalar && e.isTraversable(derefType(ft)) {
+				fields = e.schema(fields, derefType(ft), namespace, visited)
+			}
+
+			continue
+		}
+
+		ns := f.name
+		if namespace != "" {
+			ns = namespace + string(namespaceSeparator) + f.name
+		}
+
+		if e.isTraversable(derefType(ft)) {
+			fields = e.schema(fields, derefType(ft), ns, visited)
+
+			continue
+		}
+
+		fields = append(fields, FieldSchema{
+			Namespace: ns,
+			Type:      ft,
+			Required:  f.isRequired,
+			Options:   append([]string(nil), f.options...),
+		})
+	}
+
+	return fields
+}
+
+// isTraversable checks if type is a struct that is encoded as a set of fields.
+func (e *Encoder) isTraversable(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct || isTimeType(typ) {
+		return false
+	}
+
+	if _, ok := e.customTypeFuncs[typ]; ok {
+		return false
+	}
+
+	return !typ.Implements(textMarshalerType) && !typ.Implements(formFielderType)
+}
@@ -0,0 +1,66 @@
+package form
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrInvalidToken is returned by Decoder.DecodeToken for malformed token.
+	ErrInvalidToken = errors.New("form: invalid token")
+
+	// ErrTokenSignature is returned by Decoder.DecodeToken if token signature is not verified.
+	ErrTokenSignature = errors.New("form: token signature mismatch")
+)
+
+// EncodeToken encodes v into a URL-safe token of base64 payload and signature separated with ".",
+// e.g. for stateless pagination cursors.
+//
+// Payload is canonical form of encoded values, that is url.Values.Encode with keys sorted,
+// signature is computed with signFn over payload, e.g. with HMAC.
+func (e *Encoder) EncodeToken(v interface{}, signFn func(payload []byte) []byte) (string, error) {
+	values, err := e.Encode(v)
+	if err != nil {
+		return "", err
+	}
+
+	payload := []byte(values.Encode())
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signFn(payload)), nil
+}
+
+// DecodeToken verifies signature of token produced with Encoder.EncodeToken and decodes its payload into v.
+//
+// Token fails decoding with ErrInvalidToken if it is malformed or with ErrTokenSignature
+// if verifyFn returns false for its payload and signature.
+func (d *Decoder) DecodeToken(v interface{}, token string, verifyFn func(payload, signature []byte) bool) error {
+	p, s, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("%w: missing signature", ErrInvalidToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+
+	if !verifyFn(payload, signature) {
+		return ErrTokenSignature
+	}
+
+	values, err := url.ParseQuery(string(payload))
+	if err != nil {
+		return fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+
+	return d.Decode(v, values)
+}
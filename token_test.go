@@ -0,0 +1,74 @@
+package form_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/form/v5"
+)
+
+func TestEncoder_EncodeToken(t *testing.T) {
+	type Cursor struct {
+		After string   `form:"after"`
+		Limit int      `form:"limit"`
+		Sort  []string `form:"sort"`
+	}
+
+	secret := []byte("secret")
+
+	sign := func(payload []byte) []byte {
+		h := hmac.New(sha256.New, secret)
+		h.Write(payload)
+
+		return h.Sum(nil)
+	}
+
+	verify := func(payload, signature []byte) bool {
+		return hmac.Equal(sign(payload), signature)
+	}
+
+	enc := form.NewEncoder()
+	dec := form.NewDecoder()
+
+	c := Cursor{After: "id/42 &x", Limit: 10, Sort: []string{"-created", "name"}}
+
+	token, err := enc.EncodeToken(c, sign)
+	require.NoError(t, err)
+	assert.NotContains(t, token, "=")
+	assert.NotContains(t, token, "/")
+	assert.NotContains(t, token, "+")
+
+	var decoded Cursor
+
+	require.NoError(t, dec.DecodeToken(&decoded, token, verify))
+	assert.Equal(t, c, decoded)
+
+	// token is canonical, same values produce same token
+	again, err := enc.EncodeToken(c, sign)
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+
+	p, s, _ := strings.Cut(token, ".")
+
+	tampered, err := enc.EncodeToken(Cursor{After: "id/1", Limit: 10}, sign)
+	require.NoError(t, err)
+
+	tp, _, _ := strings.Cut(tampered, ".")
+
+	err = dec.DecodeToken(&decoded, tp+"."+s, verify)
+	assert.EqualError(t, err, "form: token signature mismatch")
+	assert.True(t, errors.Is(err, form.ErrTokenSignature))
+
+	err = dec.DecodeToken(&decoded, p, verify)
+	assert.EqualError(t, err, "form: invalid token: missing signature")
+	assert.True(t, errors.Is(err, form.ErrInvalidToken))
+
+	err = dec.DecodeToken(&decoded, "!!."+s, verify)
+	assert.True(t, errors.Is(err, form.ErrInvalidToken))
+	assert.False(t, errors.Is(err, form.ErrTokenSignature))
+}
@@ -1,8 +1,10 @@
 package form
 
 import (
+	"encoding"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 // ExtractType gets the actual underlying type of field value.
@@ -42,6 +44,61 @@ func parseBool(str string) (bool, error) {
 	return false, &strconv.NumError{Func: "ParseBool", Num: str, Err: strconv.ErrSyntax}
 }
 
+// derefType returns type with pointers dereferenced.
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// isDigits checks if non-empty string consists of ASCII digits only.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// escapeMapKey percent-encodes characters that have special meaning in namespace.
+func escapeMapKey(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	n := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '%', '.', '[', ']':
+			n++
+		}
+	}
+
+	if n == 0 {
+		return s
+	}
+
+	b := make([]byte, 0, len(s)+2*n)
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '%', '.', '[', ']':
+			b = append(b, '%', hex[c>>4], hex[c&15])
+		default:
+			b = append(b, c)
+		}
+	}
+
+	return string(b)
+}
+
 // hasValue determines if a reflect.Value is it's default value.
 func hasValue(field reflect.Value) bool {
 	switch field.Kind() {
@@ -51,3 +108,140 @@ func hasValue(field reflect.Value) bool {
 		return field.IsValid() && field.Interface() != reflect.Zero(field.Type()).Interface()
 	}
 }
+
+// isDefault determines if a reflect.Value deeply equals to default instance of its type.
+func isDefault(field reflect.Value) bool {
+	v, kind := ExtractType(field)
+
+	if kind == reflect.Ptr || kind == reflect.Interface || kind == reflect.Invalid {
+		return true
+	}
+
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// isTimeType reports whether type is time.Time or a struct that only embeds time.Time,
+// e.g. type Timestamp struct{ time.Time }.
+func isTimeType(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+
+	return t.Kind() == reflect.Struct && t.NumField() == 1 && t.Field(0).Anonymous && t.Field(0).Type == timeType
+}
+
+// isStructType reports whether type dereferences to a struct that is not a time type.
+func isStructType(t reflect.Type) bool {
+	t = derefType(t)
+
+	return t.Kind() == reflect.Struct && !isTimeType(t)
+}
+
+// hasName reports whether names contain name.
+func hasName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyKeyCase returns name in case of `keycase` tag option, "lower" or "upper", other names are returned as is.
+func applyKeyCase(name, keyCase string) string {
+	switch keyCase {
+	case "lower":
+		return strings.ToLower(name)
+	case "upper":
+		return strings.ToUpper(name)
+	default:
+		return name
+	}
+}
+
+// timeValue returns time.Time value of a value of time type.
+func timeValue(v reflect.Value) reflect.Value {
+	if v.Type() == timeType {
+		return v
+	}
+
+	return v.Field(0)
+}
+
+// equalsDefault reports whether a scalar value equals default value of field tag.
+func equalsDefault(current reflect.Value, def string) bool {
+	v, kind := ExtractType(current)
+
+	switch kind {
+	case reflect.String:
+		return v.String() == def
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(def, 10, 64)
+
+		return err == nil && v.Int() == i
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(def, 10, 64)
+
+		return err == nil && v.Uint() == u
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(def, v.Type().Bits())
+
+		return err == nil && v.Float() == f
+	case reflect.Bool:
+		b, err := parseBool(def)
+
+		return err == nil && v.Bool() == b
+	default:
+		return false
+	}
+}
+
+// pluralize returns plural form of name with simple English rules.
+func pluralize(name string) string {
+	switch {
+	case name == "":
+		return name
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case len(name) > 1 && name[len(name)-1] == 'y' && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	default:
+		return name + "s"
+	}
+}
+
+// sliceKey returns name transformed with fn if t is a slice or array type.
+func sliceKey(name string, t reflect.Type, fn func(string) string) string {
+	if fn == nil {
+		return name
+	}
+
+	if k := derefType(t).Kind(); k != reflect.Slice && k != reflect.Array {
+		return name
+	}
+
+	return fn(name)
+}
+
+// numberValue returns value of integer or float v as float64 and reports whether v is a number.
+func numberValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() { //nolint:exhaustive // Only numbers are converted.
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// isTextUnmarshaler checks if addressable v implements encoding.TextUnmarshaler.
+func isTextUnmarshaler(v reflect.Value) bool {
+	_, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+
+	return ok
+}